@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/sillkiw/wb-l0/internal/dlq"
 	"github.com/sillkiw/wb-l0/internal/kafka"
 	"github.com/sillkiw/wb-l0/internal/logger"
+	"github.com/sillkiw/wb-l0/internal/retry"
 	"github.com/sillkiw/wb-l0/internal/storage"
 )
 
@@ -40,21 +42,68 @@ func main() {
 
 	//  DLQ
 	var dlqPub consumer.DLQ
-	if cfg.DLQTopic != "" {
+	switch cfg.DLQBackend {
+	case "postgres":
+		pub, err := dlq.NewPostgresStore(cfg.PostgresDSN)
+		if err != nil {
+			logg.Error("DLQ init failed", slog.Any("err", err))
+		} else {
+			dlqPub = pub
+			defer pub.Close()
+			logg.Info("DLQ enabled", slog.String("backend", "postgres"))
+		}
+	case "noop":
+		dlqPub = dlq.NoopPublisher{}
+		logg.Warn("DLQ noop: failed messages are dropped")
+	default:
+		if cfg.DLQTopic == "" {
+			logg.Warn("DLQ disabled (KAFKA_DLQ_TOPIC not set)")
+			break
+		}
 		pub, err := dlq.NewKafkaPublisher(cfg.KafkaBrokers, cfg.DLQTopic)
 		if err != nil {
 			logg.Error("DLQ init failed", slog.Any("err", err))
 		} else {
 			dlqPub = pub
 			defer pub.Close()
-			logg.Info("DLQ enabled", slog.String("topic", cfg.DLQTopic))
+			logg.Info("DLQ enabled", slog.String("backend", "kafka"), slog.String("topic", cfg.DLQTopic))
+		}
+	}
+
+	// ретраи
+	var retrier consumer.Retrier
+	if len(cfg.RetryTiers) > 0 {
+		tiers := make([]retry.Tier, 0, len(cfg.RetryTiers))
+		for _, t := range cfg.RetryTiers {
+			tiers = append(tiers, retry.Tier{Topic: t.Topic, Delay: t.Delay})
+		}
+		pub, err := retry.NewPublisher(cfg.KafkaBrokers, tiers)
+		if err != nil {
+			logg.Error("retry init failed", slog.Any("err", err))
+		} else {
+			retrier = pub
+			defer pub.Close()
+			logg.Info("retries enabled",
+				slog.Int("tiers", len(tiers)),
+				slog.Int("max_attempts", cfg.RetryMaxAttempts),
+			)
 		}
 	} else {
-		logg.Warn("DLQ disabled (KAFKA_DLQ_TOPIC not set)")
+		logg.Warn("retries disabled (KAFKA_RETRY_TOPICS not set)")
 	}
 
 	// handler
-	h := consumer.NewHandler(store, dlqPub, logg)
+	h := consumer.NewHandler(store, dlqPub, logg, consumer.Options{
+		Retry:       retrier,
+		MaxAttempts: cfg.RetryMaxAttempts,
+		GroupID:     cfg.KafkaGroupID,
+		Topic:       cfg.KafkaTopic,
+		Instance:    cfg.InstanceID,
+		StaleToDLQ:  cfg.StaleToDLQ,
+	})
+
+	// позиции чтения берём из БД, а не из брокера
+	syncOffsets(cfg, store, logg)
 
 	//  kafka-риддер
 	r := kafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroupID)
@@ -63,6 +112,7 @@ func main() {
 			logg.Error("failed to close Kafka reader", slog.Any("err", err))
 		}
 	}()
+	src := consumer.WithLedger(r, store, cfg.KafkaGroupID, cfg.KafkaTopic)
 
 	logg.Info("Consumer started",
 		slog.String("broker", strings.Join(cfg.KafkaBrokers, ",")),
@@ -80,12 +130,60 @@ func main() {
 		os.Exit(1)
 	}()
 
+	// читатели топиков ретраев — по своей группе на каждый топик
+	var retryWG sync.WaitGroup
+	if retrier != nil {
+		for _, t := range cfg.RetryTiers {
+			rr := kafka.NewReader(cfg.KafkaBrokers, t.Topic, cfg.KafkaGroupID+"."+t.Topic)
+			defer rr.Close()
+			retryWG.Add(1)
+			go func() {
+				defer retryWG.Done()
+				consumer.RunDelayed(ctx, rr, t.Delay, h, logg)
+			}()
+		}
+	}
+
+	switch {
+	case cfg.BatchSize > 1:
+		logg.Info("micro-batching enabled",
+			slog.Int("batch_size", cfg.BatchSize),
+			slog.Duration("linger", cfg.BatchLinger),
+		)
+		consumer.NewBatcher(h, src, consumer.BatchConfig{
+			MaxSize:   cfg.BatchSize,
+			MaxLinger: cfg.BatchLinger,
+		}, logg).Run(ctx)
+	case cfg.Workers > 1:
+		logg.Info("worker pool enabled",
+			slog.Int("workers", cfg.Workers),
+			slog.String("dispatch", cfg.Dispatch),
+		)
+		consumer.NewPool(h, src, consumer.PoolConfig{
+			Workers:  cfg.Workers,
+			Dispatch: cfg.Dispatch,
+		}, logg).Run(ctx)
+	default:
+		runSequential(ctx, src, h, logg)
+	}
+	retryWG.Wait()
+
+	logg.Info("Consumer stopped",
+		slog.Int64("stale_skipped", h.StaleCount()),
+		slog.String("broker", strings.Join(cfg.KafkaBrokers, ",")),
+		slog.String("topic", cfg.KafkaTopic),
+		slog.String("group_id", cfg.KafkaGroupID),
+	)
+}
+
+// runSequential — обработка по одному сообщению: fetch → handle → commit.
+func runSequential(ctx context.Context, r consumer.Source, h *consumer.Handler, logg *slog.Logger) {
 	for {
 		msg, err := r.FetchMessage(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
 				logg.Info("shutdown: stop reading and exit loop")
-				break
+				return
 			}
 			logg.Error("kafka read failed", slog.Any("err", err))
 			time.Sleep(time.Second)
@@ -93,30 +191,34 @@ func main() {
 		}
 
 		if commit := h.Handle(ctx, msg); commit {
-			_ = commitWithTimeout(context.Background(), r, msg, consumer.MsgLogger(logg, msg))
+			_ = consumer.CommitWithTimeout(context.Background(), r, msg, consumer.MsgLogger(logg, msg))
 		}
 	}
-
-	logg.Info("Consumer stopped",
-		slog.String("broker", strings.Join(cfg.KafkaBrokers, ",")),
-		slog.String("topic", cfg.KafkaTopic),
-		slog.String("group_id", cfg.KafkaGroupID),
-	)
 }
 
-// commitWithTimeout хелпер с таймаутом
-func commitWithTimeout(
-	parent context.Context,
-	r *kafka.Consumer,
-	msg kafka.Message,
-	log *slog.Logger,
-) error {
-	commitCtx, cancel := context.WithTimeout(parent, 5*time.Second)
+// syncOffsets переносит сохранённые в БД позиции группы в брокер до того,
+// как читатель присоединится к группе. Если в группе уже есть участники
+// (другие реплики), брокер коммит отклонит — тогда повторы отсечёт журнал
+// processed_messages.
+func syncOffsets(cfg config.ConsumerConfig, store *storage.Storage, logg *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := r.CommitMessages(commitCtx, msg); err != nil {
-		log.Error("commit failed; message will be reprocessed", slog.Any("err", err))
-		return err
+
+	offsets, err := store.LoadOffsets(ctx, cfg.KafkaGroupID, cfg.KafkaTopic)
+	if err != nil {
+		logg.Warn("load offsets from db failed", slog.Any("err", err))
+		return
+	}
+	if len(offsets) == 0 {
+		logg.Info("no stored offsets in db, using broker offsets")
+		return
+	}
+	if err := kafka.CommitGroupOffsets(ctx, cfg.KafkaBrokers, cfg.KafkaGroupID, cfg.KafkaTopic, offsets); err != nil {
+		logg.Warn("sync offsets to broker failed, relying on processed_messages",
+			slog.Int("partitions", len(offsets)),
+			slog.Any("err", err),
+		)
+		return
 	}
-	log.Debug("offset commited")
-	return nil
+	logg.Info("offsets restored from db", slog.Int("partitions", len(offsets)))
 }
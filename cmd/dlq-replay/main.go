@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/sillkiw/wb-l0/internal/config"
+	"github.com/sillkiw/wb-l0/internal/dlq"
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/logger"
+	"github.com/sillkiw/wb-l0/internal/storage"
+	"github.com/sillkiw/wb-l0/internal/validation"
+)
+
+const (
+	modeRepublish = "republish" // обратно в исходный топик, через консюмер
+	modeSave      = "save"      // сразу в БД, минуя Kafka
+)
+
+const (
+	sourceKafka    = "kafka"    // DLQ-топик (DLQ_BACKEND=kafka)
+	sourcePostgres = "postgres" // таблица dead_letters (DLQ_BACKEND=postgres)
+)
+
+// dbPageSize — сколько записей dead_letters читать за один запрос.
+const dbPageSize = 500
+
+// filter — какие DLQ-сообщения переигрывать. Нулевые поля не фильтруют.
+type filter struct {
+	reasons    map[string]bool
+	since      time.Time
+	until      time.Time
+	partition  int // -1 — любая
+	offsetFrom int64
+	offsetTo   int64 // -1 — без верхней границы
+}
+
+func (f filter) match(env dlq.Envelope) bool {
+	if len(f.reasons) > 0 && !f.reasons[env.Reason] {
+		return false
+	}
+	if !f.since.IsZero() && env.Timestamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !env.Timestamp.Before(f.until) {
+		return false
+	}
+	if f.partition >= 0 && env.OriginalPartition != f.partition {
+		return false
+	}
+	if env.OriginalOffset < f.offsetFrom {
+		return false
+	}
+	if f.offsetTo >= 0 && env.OriginalOffset > f.offsetTo {
+		return false
+	}
+	return true
+}
+
+// reasonStats — строка итогового отчёта по одной причине.
+type reasonStats struct {
+	matched, valid, invalid, replayed, skipped, failed int
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env not found")
+	}
+	cfg := config.LoadReplay()
+
+	var (
+		source     = flag.String("source", sourceKafka, "where to read dead letters: kafka|postgres")
+		topic      = flag.String("topic", cfg.DLQTopic, "DLQ topic to read")
+		reasons    = flag.String("reason", "", "comma-separated reasons to replay (empty — all)")
+		since      = flag.String("since", "", "only messages dead-lettered at or after this time (RFC3339)")
+		until      = flag.String("until", "", "only messages dead-lettered before this time (RFC3339)")
+		partition  = flag.Int("partition", -1, "original partition (-1 — any)")
+		offsetFrom = flag.Int64("offset-from", 0, "lowest original offset, inclusive")
+		offsetTo   = flag.Int64("offset-to", -1, "highest original offset, inclusive (-1 — no limit)")
+		apply      = flag.Bool("apply", false, "replay matched messages; without it only the report is printed")
+		mode       = flag.String("mode", modeRepublish, "how to replay: republish|save")
+		target     = flag.String("target", "", "republish into this topic instead of the original one")
+		verbose    = flag.Bool("v", false, "print every matched message")
+	)
+	flag.Parse()
+
+	logg := logger.New(cfg.LogFormat, cfg.LogLevel)
+
+	f := filter{partition: *partition, offsetFrom: *offsetFrom, offsetTo: *offsetTo}
+	for _, r := range strings.Split(*reasons, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			if f.reasons == nil {
+				f.reasons = make(map[string]bool)
+			}
+			f.reasons[r] = true
+		}
+	}
+	var err error
+	if f.since, err = parseTime(*since); err != nil {
+		logg.Error("bad -since", slog.Any("err", err))
+		os.Exit(2)
+	}
+	if f.until, err = parseTime(*until); err != nil {
+		logg.Error("bad -until", slog.Any("err", err))
+		os.Exit(2)
+	}
+	if *mode != modeRepublish && *mode != modeSave {
+		logg.Error("bad -mode, want republish|save", slog.String("mode", *mode))
+		os.Exit(2)
+	}
+	if *source != sourceKafka && *source != sourcePostgres {
+		logg.Error("bad -source, want kafka|postgres", slog.String("source", *source))
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var r *replayer
+	if *apply {
+		r, err = newReplayer(cfg, *mode, *target)
+		if err != nil {
+			logg.Error("replay init failed", slog.Any("err", err))
+			os.Exit(1)
+		}
+		defer r.Close()
+	}
+
+	stats := make(map[string]*reasonStats)
+	undecodable := 0
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	// handle обрабатывает один конверт; true — заказ переигран успешно
+	handle := func(env dlq.Envelope, from string) bool {
+		if !f.match(env) {
+			return false
+		}
+
+		st := stats[env.Reason]
+		if st == nil {
+			st = &reasonStats{}
+			stats[env.Reason] = st
+		}
+		st.matched++
+
+		// перепроверяем текущими правилами: их могли исправить после попадания в DLQ
+		order, verr := revalidate(env.Payload)
+		verdict := "valid"
+		if verr != nil {
+			st.invalid++
+			verdict = "invalid: " + verr.Error()
+		} else {
+			st.valid++
+		}
+
+		ok := false
+		if r != nil && verr == nil {
+			err := r.replay(ctx, env, order, from)
+			switch {
+			case errors.Is(err, storage.ErrStale):
+				st.skipped++
+				verdict = "skipped: newer version in db"
+			case err != nil:
+				st.failed++
+				verdict = "replay failed: " + err.Error()
+				logg.Error("replay failed",
+					slog.String("replayed_from", from),
+					slog.String("order_uid", order.OrderUID),
+					slog.Any("err", err),
+				)
+			default:
+				st.replayed++
+				verdict = "replayed"
+				ok = true
+			}
+		}
+
+		if *verbose {
+			fmt.Fprintf(out, "%s:%d:%d\t%s\t%s\t%s\t%s\n",
+				env.OriginalTopic, env.OriginalPartition, env.OriginalOffset,
+				env.Key, env.Reason, env.Timestamp.Format(time.RFC3339), verdict)
+		}
+		return ok
+	}
+
+	if *source == sourcePostgres {
+		err = scanDeadLetters(ctx, cfg.PostgresDSN, f, *apply, handle, logg)
+	} else {
+		err = kafka.ScanTopic(ctx, cfg.KafkaBrokers, *topic, func(m kafka.Message) error {
+			var env dlq.Envelope
+			if err := json.Unmarshal(m.Value, &env); err != nil {
+				undecodable++
+				logg.Warn("skip undecodable DLQ message",
+					slog.Int("partition", m.Partition),
+					slog.Int64("offset", m.Offset),
+					slog.Any("err", err),
+				)
+				return nil
+			}
+			handle(env, fmt.Sprintf("%s:%d:%d", m.Topic, m.Partition, m.Offset))
+			return nil
+		})
+	}
+	if *verbose {
+		_ = out.Flush()
+		fmt.Println()
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logg.Error("scan DLQ failed", slog.String("source", *source), slog.Any("err", err))
+	}
+
+	printReport(stats, undecodable, *apply)
+
+	failed := 0
+	for _, st := range stats {
+		failed += st.failed
+	}
+	if err != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// scanDeadLetters читает из dead_letters записи со статусом new постранично
+// и отдаёт их handle. Успешно переигранные записи помечаются resolved, чтобы
+// повторный запуск их не трогал; без apply статусы не меняются.
+func scanDeadLetters(ctx context.Context, dsn string, f filter, apply bool,
+	handle func(env dlq.Envelope, from string) bool, logg *slog.Logger) error {
+	store, err := dlq.NewPostgresStore(dsn)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	defer store.Close()
+
+	lf := dlq.ListFilter{Status: dlq.StatusNew, Since: f.since, Until: f.until, Limit: dbPageSize}
+	if len(f.reasons) == 1 {
+		for r := range f.reasons {
+			lf.Reason = r
+		}
+	}
+	for {
+		page, err := store.List(ctx, lf)
+		if err != nil {
+			return err
+		}
+		for _, d := range page {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !handle(d.Envelope, fmt.Sprintf("dead_letters:%d", d.ID)) || !apply {
+				continue
+			}
+			if _, err := store.SetStatus(ctx, d.ID, dlq.StatusResolved); err != nil {
+				// заказ уже переигран; запись останется new и попадёт в следующий запуск
+				logg.Error("mark dead letter resolved failed", slog.Int64("id", d.ID), slog.Any("err", err))
+			}
+		}
+		if len(page) < lf.Limit {
+			return nil
+		}
+		lf.After = page[len(page)-1].ID
+	}
+}
+
+// revalidate разбирает payload так же, как консюмер, и проверяет заказ.
+func revalidate(payload []byte) (domain.Order, error) {
+	var order domain.Order
+	if err := validation.DecodeStrict(payload, &order); err != nil {
+		return domain.Order{}, err
+	}
+	if verrs := validation.ValidateOrder(order); verrs != nil {
+		return domain.Order{}, verrs
+	}
+	return order, nil
+}
+
+func printReport(stats map[string]*reasonStats, undecodable int, applied bool) {
+	reasons := make([]string, 0, len(stats))
+	for r := range stats {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if applied {
+		fmt.Fprintln(w, "REASON\tMATCHED\tVALID\tINVALID\tREPLAYED\tSKIPPED\tFAILED")
+	} else {
+		fmt.Fprintln(w, "REASON\tMATCHED\tVALID\tINVALID")
+	}
+	var total reasonStats
+	for _, r := range reasons {
+		st := stats[r]
+		total.matched += st.matched
+		total.valid += st.valid
+		total.invalid += st.invalid
+		total.replayed += st.replayed
+		total.skipped += st.skipped
+		total.failed += st.failed
+		printRow(w, r, *st, applied)
+	}
+	printRow(w, "TOTAL", total, applied)
+	_ = w.Flush()
+
+	if undecodable > 0 {
+		fmt.Printf("\nundecodable DLQ messages skipped: %d\n", undecodable)
+	}
+	if !applied {
+		fmt.Println("\ndry run: nothing replayed, add -apply to replay valid messages")
+	}
+}
+
+func printRow(w *tabwriter.Writer, name string, st reasonStats, applied bool) {
+	if applied {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n", name, st.matched, st.valid, st.invalid, st.replayed, st.skipped, st.failed)
+		return
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", name, st.matched, st.valid, st.invalid)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// replayer возвращает сообщение в обработку: публикует в исходный топик
+// или сохраняет заказ в БД напрямую.
+type replayer struct {
+	mode      string
+	target    string
+	brokers   []string
+	producers map[string]*kafka.Producer
+	store     *storage.Storage
+}
+
+func newReplayer(cfg config.ReplayConfig, mode, target string) (*replayer, error) {
+	r := &replayer{mode: mode, target: target, brokers: cfg.KafkaBrokers}
+	if mode == modeSave {
+		store, err := storage.NewStorage(cfg.PostgresDSN)
+		if err != nil {
+			return nil, fmt.Errorf("connect to database: %w", err)
+		}
+		r.store = store
+		return r, nil
+	}
+	r.producers = make(map[string]*kafka.Producer)
+	return r, nil
+}
+
+func (r *replayer) replay(ctx context.Context, env dlq.Envelope, order domain.Order, from string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if r.mode == modeSave {
+		// без Origin: журнал processed_messages ведётся только для сообщений основного топика.
+		// Версия — время исходного сообщения: старый заказ из DLQ не перетрёт более новый
+		// (тогда SaveOrder вернёт storage.ErrStale).
+		return r.store.SaveOrder(ctx, storage.Record{
+			Order:   order,
+			Version: env.Version(),
+			Source: storage.Source{
+				Topic:     env.OriginalTopic,
+				Partition: env.OriginalPartition,
+				Offset:    env.OriginalOffset,
+				Key:       env.Key,
+			},
+		})
+	}
+
+	topic := r.target
+	if topic == "" {
+		topic = env.OriginalTopic
+	}
+	if topic == "" {
+		return errors.New("original topic unknown, use -target")
+	}
+	p := r.producers[topic]
+	if p == nil {
+		p = kafka.NewProducer(r.brokers, topic)
+		r.producers[topic] = p
+	}
+	// у нового сообщения своё время; версию заказа консюмер возьмёт из заголовка
+	return p.SendWithHeaders(ctx, []byte(env.Key), env.Payload, []kafka.Header{
+		{Key: kafka.HeaderReplayedFrom, Value: []byte(from)},
+		{Key: kafka.HeaderOriginalTimestamp, Value: []byte(strconv.FormatInt(env.Version().UnixMilli(), 10))},
+	})
+}
+
+func (r *replayer) Close() {
+	for _, p := range r.producers {
+		_ = p.Close()
+	}
+	if r.store != nil {
+		_ = r.store.Close()
+	}
+}
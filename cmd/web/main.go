@@ -3,18 +3,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 
 	"github.com/sillkiw/wb-l0/internal/app"
 	"github.com/sillkiw/wb-l0/internal/config"
+	"github.com/sillkiw/wb-l0/internal/dlq"
 	"github.com/sillkiw/wb-l0/internal/httpserver"
+	"github.com/sillkiw/wb-l0/internal/kafka"
 	"github.com/sillkiw/wb-l0/internal/logger"
 	"github.com/sillkiw/wb-l0/internal/storage"
 )
@@ -43,15 +47,47 @@ func main() {
 		ui = httpserver.EmbeddedUI()
 	}
 
+	opts := httpserver.Options{
+		CacheSize:   web.CacheSize,
+		CacheTTL:    web.CacheTTL,
+		CacheShards: web.CacheShards,
+
+		StaleWhileRevalidate: web.CacheStaleWhileRevalidate,
+		StaleIfError:         web.CacheStaleIfError,
+		CacheMaxBytes:        web.CacheMaxBytes,
+		CacheMaxItemBytes:    web.CacheMaxItemBytes,
+		NegativeSize:         web.NegativeCacheSize,
+		NegativeTTL:          web.NegativeCacheTTL,
+		FeedTTL:              web.CacheFeedTTL,
+		CacheControl:         web.CacheControl,
+		AdminToken:           web.AdminToken,
+	}
+
+	if web.AdminToken != "" {
+		log.Info("admin API enabled")
+	}
+
+	// API для DLQ в Postgres (под токеном админки)
+	if web.DeadLettersAPI && web.AdminToken == "" {
+		log.Warn("DLQ_API requires ADMIN_TOKEN, dead letters API disabled")
+	}
+	if web.DeadLettersAPI && web.AdminToken != "" {
+		dead, err := dlq.NewPostgresStore(web.PostgresDSN)
+		if err != nil {
+			log.Error("dead letters store init failed", slog.Any("err", err))
+			os.Exit(1)
+		}
+		defer dead.Close()
+		opts.DeadLetters = dead
+		log.Info("dead letters API enabled")
+	}
+
 	// HTTP сервер с роутами и кэшем
 	hs := httpserver.New(
 		log,
 		store, // реализует httpserver.OrderStore
 		ui,
-		httpserver.Options{
-			CacheSize: web.CacheSize,
-			CacheTTL:  web.CacheTTL,
-		},
+		opts,
 	)
 
 	a := app.New(
@@ -69,7 +105,71 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Инвалидация кэша по изменениям в БД
+	if web.CacheListen {
+		go listenOrderChanges(ctx, log, hs, web.PostgresDSN)
+	}
+
+	// Свежие заказы из топика сразу в кэш (в БД не пишет)
+	if web.CacheFeed {
+		r := kafka.NewTailReader(web.KafkaBrokers, web.KafkaTopic, web.FeedGroupID)
+		defer r.Close()
+		log.Info("cache feed enabled",
+			slog.String("topic", web.KafkaTopic),
+			slog.String("group_id", web.FeedGroupID),
+		)
+		go hs.FeedCache(ctx, r)
+	}
+
+	// Прогрев кэша в фоне: /healthz отвечает сразу, /readyz — после прогрева
+	go warmCache(ctx, log, hs, web.CacheWarmup, web.CacheWarmupTimeout)
+
 	if err := a.Run(ctx); err != nil {
 		log.Error("server error", slog.Any("err", err))
 	}
 }
+
+// listenOrderChanges держит LISTEN orders_changed: изменённые заказы
+// сбрасываются из кэша, после разрыва соединения кэш очищается целиком.
+func listenOrderChanges(ctx context.Context, log *slog.Logger, hs *httpserver.Server, dsn string) {
+	err := storage.ListenOrderChanges(ctx, dsn, log, hs.OrderChanged, func() {
+		hs.PurgeCache()
+		log.Warn("orders listener gap, cache purged")
+	})
+	if err != nil {
+		log.Error("orders listener failed, cache relies on TTL", slog.Any("err", err))
+	}
+}
+
+// warmCache загружает последние заказы в кэш и помечает сервер готовым.
+// Ошибка прогрева не фатальна: кэш доберёт заказы по промахам.
+func warmCache(ctx context.Context, log *slog.Logger, hs *httpserver.Server, limit int, timeout time.Duration) {
+	defer hs.SetReady(true)
+	if limit <= 0 {
+		log.Info("cache warmup disabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Info("cache warmup started", slog.Int("limit", limit))
+	start := time.Now()
+	n, err := hs.WarmCache(ctx, limit, 500)
+	if errors.Is(err, httpserver.ErrWarmupRunning) {
+		log.Info("cache warmup skipped, already running")
+		return
+	}
+	if err != nil {
+		log.Warn("cache warmup failed",
+			slog.Int("loaded", n),
+			slog.Duration("dur", time.Since(start)),
+			slog.Any("err", err),
+		)
+		return
+	}
+	log.Info("cache warmup done",
+		slog.Int("loaded", n),
+		slog.Duration("dur", time.Since(start)),
+	)
+}
@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Сообщения основного топика, уже применённые к БД.
+-- Пишется в той же транзакции, что и заказ: повторная доставка сообщения
+-- после сбоя между коммитом транзакции и коммитом смещения отбрасывается.
+CREATE TABLE IF NOT EXISTS processed_messages (
+    group_id     TEXT        NOT NULL,
+    topic        TEXT        NOT NULL,
+    partition    INT         NOT NULL,
+    "offset"     BIGINT      NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, topic, partition, "offset")
+);
+
+-- Позиции, с которых консюмер продолжает чтение после рестарта.
+-- Записи processed_messages ниже next_offset больше не нужны и удаляются.
+CREATE TABLE IF NOT EXISTS consumer_offsets (
+    group_id    TEXT        NOT NULL,
+    topic       TEXT        NOT NULL,
+    partition   INT         NOT NULL,
+    next_offset BIGINT      NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, topic, partition)
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Версия заказа: время исходного Kafka-сообщения.
+-- Запись с меньшей версией не перезаписывает более новое состояние.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version_ts TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Сообщения, отправленные в DLQ (DLQ_BACKEND=postgres).
+-- Поля повторяют dlq.Envelope; исходный payload хранится как есть,
+-- а если это валидный JSON — ещё и в payload_json для поиска и просмотра.
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id                 BIGSERIAL   PRIMARY KEY,
+    original_topic     TEXT        NOT NULL,
+    original_partition INT         NOT NULL,
+    original_offset    BIGINT      NOT NULL,
+    message_key        TEXT        NOT NULL DEFAULT '',
+    reason             TEXT        NOT NULL,
+    detail             TEXT        NOT NULL DEFAULT '',
+    errors             JSONB,
+    decode_error       TEXT        NOT NULL DEFAULT '',
+    attempt            INT         NOT NULL DEFAULT 1,
+    group_id           TEXT        NOT NULL DEFAULT '',
+    instance           TEXT        NOT NULL DEFAULT '',
+    payload            BYTEA       NOT NULL,
+    payload_json       JSONB,
+    first_failed_at    TIMESTAMPTZ NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status             TEXT        NOT NULL DEFAULT 'new'
+                       CHECK (status IN ('new', 'resolved', 'ignored')),
+    status_changed_at  TIMESTAMPTZ,
+    -- одно исходное сообщение попадает в таблицу один раз
+    UNIQUE (original_topic, original_partition, original_offset)
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_reason_idx ON dead_letters (reason, id DESC);
+CREATE INDEX IF NOT EXISTS dead_letters_key_idx    ON dead_letters (message_key, id DESC);
+CREATE INDEX IF NOT EXISTS dead_letters_created_idx ON dead_letters (created_at);
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Keyset-пагинация списка заказов по (date_created, order_uid).
+-- Составной индекс покрывает и прежний idx_orders_date.
+CREATE INDEX IF NOT EXISTS idx_orders_date_uid ON orders(date_created DESC, order_uid DESC);
+DROP INDEX IF EXISTS idx_orders_date;
+
+-- Фильтры /api/v1/orders
+CREATE INDEX IF NOT EXISTS idx_orders_customer_date ON orders(customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_service_date  ON orders(delivery_service, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number  ON orders(track_number);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Поиск заказа по идентификаторам оплаты и товаров (/api/v1/lookup).
+-- payments.transaction — первичный ключ, orders.track_number — индекс из 000005.
+CREATE INDEX IF NOT EXISTS idx_payments_request_id ON payments(request_id);
+CREATE INDEX IF NOT EXISTS idx_items_rid           ON items(rid);
+
+COMMIT;
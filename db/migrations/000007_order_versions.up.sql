@@ -0,0 +1,20 @@
+BEGIN;
+
+-- История заказа: каждая принятая версия (после защиты от устаревших версий)
+-- целиком, с координатами Kafka-сообщения и временем записи.
+-- Без FK на orders: история переживает удаление заказа.
+CREATE TABLE IF NOT EXISTS order_versions (
+    order_uid        TEXT        NOT NULL,
+    version          INT         NOT NULL, -- 1, 2, ... в пределах заказа
+    version_ts       TIMESTAMPTZ NOT NULL, -- версия заказа (orders.version_ts)
+    payload          JSONB       NOT NULL, -- заказ целиком, как его отдаёт API
+    source_topic     TEXT,
+    source_partition INT,
+    source_offset    BIGINT,
+    ingested_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (order_uid, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_versions_ingested ON order_versions(order_uid, ingested_at);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Откуда и когда пришёл заказ: время первой и последней записи,
+-- координаты и ключ Kafka-сообщения последней принятой версии.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS ingested_at      TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS updated_at       TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS source_topic     TEXT,
+    ADD COLUMN IF NOT EXISTS source_partition INT,
+    ADD COLUMN IF NOT EXISTS source_offset    BIGINT,
+    ADD COLUMN IF NOT EXISTS source_key       TEXT;
+
+ALTER TABLE order_versions ADD COLUMN IF NOT EXISTS source_key TEXT;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Время исходного Kafka-сообщения = версия заказа: dlq-replay передаёт его
+-- дальше, чтобы переигранный заказ не перетёр более новую версию.
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS original_timestamp TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BenchmarkCache сравнивает один LRU и Sharded под конкурентной нагрузкой:
+// 90% Get / 10% Set по 10 000 горячих ключей, кэш вмещает все ключи.
+//
+//	go test ./internal/cache -run '^$' -bench Cache -benchtime 2s
+func BenchmarkCache(b *testing.B) {
+	const keys = 10_000
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "ord_" + strconv.Itoa(i)
+	}
+
+	impls := []struct {
+		name string
+		make func() Cache[int]
+	}{
+		{"lru", func() Cache[int] { return NewLRU[int](keys, time.Hour) }},
+		{"sharded16", func() Cache[int] { return NewSharded[int](16, keys, time.Hour) }},
+		{"sharded64", func() Cache[int] { return NewSharded[int](64, keys, time.Hour) }},
+	}
+	for _, impl := range impls {
+		for _, g := range []int{1, 8, 64} {
+			b.Run(impl.name+"/goroutines-"+strconv.Itoa(g), func(b *testing.B) {
+				c := impl.make()
+				for i, k := range names {
+					c.Set(k, i)
+				}
+				b.ResetTimer()
+
+				var wg sync.WaitGroup
+				per := b.N / g
+				for w := range g {
+					n := per
+					if w == 0 {
+						n += b.N % g
+					}
+					wg.Add(1)
+					go func(w, n int) {
+						defer wg.Done()
+						x := uint32(w*7919 + 1)
+						for i := 0; i < n; i++ {
+							x ^= x << 13
+							x ^= x >> 17
+							x ^= x << 5
+							k := names[x%keys]
+							if x%10 == 0 {
+								c.Set(k, i)
+							} else {
+								c.Get(k)
+							}
+						}
+					}(w, n)
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
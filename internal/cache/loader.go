@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadFunc загружает значение по ключу из источника (обычно из БД).
+type LoadFunc[V any] func(ctx context.Context, key string) (V, error)
+
+// LoaderStats — счётчики Loader с момента создания.
+type LoaderStats struct {
+	Loads  int64 `json:"loads"`  // реальных вызовов LoadFunc
+	Shared int64 `json:"shared"` // запросов, дождавшихся чужой загрузки
+}
+
+// Loader склеивает одновременные промахи по одному ключу: LoadFunc для ключа
+// выполняется не более одного раза за раз, остальные вызывающие ждут её результат.
+type Loader[V any] struct {
+	load    LoadFunc[V]
+	timeout time.Duration
+	onLoad  func(key string, v V, err error)
+
+	mu    sync.Mutex
+	calls map[string]*call[V]
+
+	loads  atomic.Int64
+	shared atomic.Int64
+}
+
+type call[V any] struct {
+	done      chan struct{}
+	val       V
+	err       error
+	forgotten bool // сброшена Forget: результат не уходит в onLoad
+}
+
+// NewLoader создаёт Loader. timeout ограничивает одну загрузку ключа
+// (0 — без ограничения сверх контекста).
+func NewLoader[V any](load LoadFunc[V], timeout time.Duration) *Loader[V] {
+	return &Loader[V]{
+		load:    load,
+		timeout: timeout,
+		calls:   make(map[string]*call[V]),
+	}
+}
+
+// SetOnLoad задаёт fn, которая получает результат каждой загрузки (обычно
+// чтобы положить его в кэш), кроме сброшенных Forget. fn вызывается под
+// блокировкой загрузчика, поэтому после возврата Forget результат старой
+// загрузки в неё уже не попадёт. Вызывать до первого Load.
+func (l *Loader[V]) SetOnLoad(fn func(key string, v V, err error)) {
+	l.onLoad = fn
+}
+
+// Forget отвязывает текущую загрузку key: её результат получат те, кто уже
+// ждёт, но не onLoad, а следующий Load начнёт новую загрузку. Нужен, когда
+// значение в источнике изменилось, пока загрузка шла.
+func (l *Loader[V]) Forget(key string) {
+	l.mu.Lock()
+	if c, ok := l.calls[key]; ok {
+		c.forgotten = true
+		delete(l.calls, key)
+	}
+	l.mu.Unlock()
+}
+
+// ForgetAll — Forget для всех текущих загрузок.
+func (l *Loader[V]) ForgetAll() {
+	l.mu.Lock()
+	for key, c := range l.calls {
+		c.forgotten = true
+		delete(l.calls, key)
+	}
+	l.mu.Unlock()
+}
+
+// Load возвращает результат LoadFunc для key; shared == true, если ответ
+// получен из загрузки, начатой другим вызывающим. Загрузка не отменяется,
+// когда уходит запустивший её клиент: её ждут остальные. ctx ограничивает
+// только ожидание этого вызывающего.
+func (l *Loader[V]) Load(ctx context.Context, key string) (v V, shared bool, err error) {
+	l.mu.Lock()
+	c, ok := l.calls[key]
+	if ok {
+		l.mu.Unlock()
+		l.shared.Add(1)
+		shared = true
+	} else {
+		c = &call[V]{done: make(chan struct{})}
+		l.calls[key] = c
+		l.mu.Unlock()
+		l.loads.Add(1)
+		go l.run(context.WithoutCancel(ctx), key, c)
+	}
+
+	select {
+	case <-c.done:
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, shared, ctx.Err()
+	}
+}
+
+func (l *Loader[V]) run(ctx context.Context, key string, c *call[V]) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	defer func() {
+		l.mu.Lock()
+		if !c.forgotten {
+			delete(l.calls, key)
+			if l.onLoad != nil {
+				l.onLoad(key, c.val, c.err)
+			}
+		}
+		l.mu.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = l.load(ctx, key)
+}
+
+// Stats возвращает текущие счётчики.
+func (l *Loader[V]) Stats() LoaderStats {
+	return LoaderStats{Loads: l.loads.Load(), Shared: l.shared.Load()}
+}
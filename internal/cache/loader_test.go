@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoaderCoalescesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l := NewLoader(func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		<-release
+		return "v:" + key, nil
+	}, time.Second)
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _, err := l.Load(context.Background(), "a")
+			if err != nil {
+				t.Errorf("Load: %v", err)
+			}
+			results[i] = v
+		}()
+	}
+	// ждём, пока все встанут в очередь за первой загрузкой
+	for l.Stats().Shared < n-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("load calls = %d, want 1", got)
+	}
+	for i, v := range results {
+		if v != "v:a" {
+			t.Fatalf("result[%d] = %q, want v:a", i, v)
+		}
+	}
+	if st := l.Stats(); st.Loads != 1 || st.Shared != n-1 {
+		t.Fatalf("stats = %+v, want loads=1 shared=%d", st, n-1)
+	}
+
+	// после завершения следующий вызов снова грузит
+	if _, shared, _ := l.Load(context.Background(), "a"); shared {
+		t.Fatal("load after completion must not be shared")
+	}
+}
+
+func TestLoaderCallerCancelDoesNotAbortLoad(t *testing.T) {
+	release := make(chan struct{})
+	l := NewLoader(func(ctx context.Context, key string) (int, error) {
+		select {
+		case <-release:
+			return 42, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := l.Load(ctx, "k")
+		done <- err
+	}()
+	for l.Stats().Loads == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("cancelled caller err = %v, want context.Canceled", err)
+	}
+
+	// загрузка продолжается, и второй вызывающий получает её результат
+	res := make(chan int)
+	go func() {
+		v, _, _ := l.Load(context.Background(), "k")
+		res <- v
+	}()
+	for l.Stats().Shared == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if v := <-res; v != 42 {
+		t.Fatalf("shared result = %d, want 42", v)
+	}
+}
+
+func TestLoaderForget(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l := NewLoader(func(ctx context.Context, key string) (int32, error) {
+		n := calls.Add(1)
+		if n == 1 {
+			<-release
+		}
+		return n, nil
+	}, time.Second)
+	var stored sync.Map
+	l.SetOnLoad(func(key string, v int32, err error) { stored.Store(v, true) })
+
+	first := make(chan int32)
+	go func() {
+		v, _, _ := l.Load(context.Background(), "k")
+		first <- v
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Forget("k")
+
+	// после Forget — новая загрузка, а не ожидание старой
+	v, shared, err := l.Load(context.Background(), "k")
+	if err != nil || shared || v != 2 {
+		t.Fatalf("Load after Forget = %d, shared=%v, err=%v; want 2, new load", v, shared, err)
+	}
+	close(release)
+	if v := <-first; v != 1 {
+		t.Fatalf("waiter of forgotten load got %d, want 1", v)
+	}
+	if _, ok := stored.Load(int32(1)); ok {
+		t.Fatal("forgotten load must not reach onLoad")
+	}
+	if _, ok := stored.Load(int32(2)); !ok {
+		t.Fatal("new load must reach onLoad")
+	}
+}
@@ -9,7 +9,24 @@ import (
 type entry[V any] struct {
 	key       string
 	value     V
-	expiresAt time.Time
+	expiresAt time.Time // нулевое — без срока
+	touchedAt time.Time // последний Get/Set
+	storedAt  time.Time // последний Set: с него считается возраст записи
+	cost      int64
+}
+
+// Stats — счётчики LRU с момента создания и его текущее состояние.
+type Stats struct {
+	Hits        int64         `json:"hits"`
+	Misses      int64         `json:"misses"`      // включая просроченные записи
+	StaleHits   int64         `json:"stale_hits"`  // просроченных значений, отданных GetStale
+	Expirations int64         `json:"expirations"` // записей, удалённых по TTL (с окном SetStaleGrace) при чтении
+	Evictions   int64         `json:"evictions"`   // записей, вытесненных по размеру или бюджету
+	Rejections  int64         `json:"rejections"`  // Set, отклонённых как слишком дорогие
+	Size        int           `json:"size"`
+	Cost        int64         `json:"cost"`           // суммарная стоимость записей (0 без CostFunc)
+	OldestAge   time.Duration `json:"oldest_age_ns"`  // возраст самой старой записи (с её Set)
+	OldestIdle  time.Duration `json:"oldest_idle_ns"` // сколько не трогали самую давнюю по LRU запись
 }
 
 type LRU[V any] struct {
@@ -18,6 +35,21 @@ type LRU[V any] struct {
 	items      map[string]*list.Element
 	maxEntries int
 	ttl        time.Duration
+	grace      time.Duration // сколько хранить просроченные записи для GetStale
+
+	costFn      func(V) int64
+	maxCost     int64
+	maxItemCost int64
+	cost        int64
+
+	hits, misses, staleHits, expirations, evictions, rejections int64
+}
+
+// CostOptions — ограничение кэша по «стоимости» записей (обычно байтам).
+type CostOptions[V any] struct {
+	Cost        func(V) int64 // стоимость значения
+	MaxCost     int64         // бюджет на весь кэш; 0 — без ограничения
+	MaxItemCost int64         // дороже — запись не кладётся; 0 — равен MaxCost
 }
 
 func NewLRU[V any](maxEntries int, ttl time.Duration) *LRU[V] {
@@ -29,46 +61,136 @@ func NewLRU[V any](maxEntries int, ttl time.Duration) *LRU[V] {
 	}
 }
 
+// NewLRUWithCost — LRU, который кроме числа записей (maxEntries, 0 — без
+// ограничения) держит суммарную стоимость в пределах o.MaxCost, вытесняя
+// самые давние записи.
+func NewLRUWithCost[V any](maxEntries int, ttl time.Duration, o CostOptions[V]) *LRU[V] {
+	c := NewLRU[V](maxEntries, ttl)
+	c.costFn = o.Cost
+	c.maxCost = o.MaxCost
+	c.maxItemCost = o.MaxItemCost
+	if c.maxItemCost <= 0 || (c.maxCost > 0 && c.maxItemCost > c.maxCost) {
+		c.maxItemCost = c.maxCost
+	}
+	return c
+}
+
 func (c *LRU[V]) Get(key string) (v V, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	v, _, ok = c.get(key, false)
+	return v, ok
+}
+
+// GetStale как Get, но в пределах окна SetStaleGrace отдаёт и просроченное
+// значение; staleFor — насколько оно просрочено (0 — свежее).
+func (c *LRU[V]) GetStale(key string) (v V, staleFor time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key, true)
+}
+
+func (c *LRU[V]) get(key string, allowStale bool) (v V, staleFor time.Duration, ok bool) {
+	ele, found := c.items[key]
+	if !found {
+		c.misses++
+		return v, 0, false
+	}
+	en := ele.Value.(*entry[V])
+	now := time.Now()
+	if !en.expiresAt.IsZero() && now.After(en.expiresAt) {
+		staleFor = now.Sub(en.expiresAt)
+		c.misses++
+		if staleFor > c.grace {
+			c.removeElement(ele)
+			c.expirations++
+			return v, 0, false
+		}
+		if !allowStale {
+			return v, 0, false
+		}
+		c.staleHits++
+	} else {
+		c.hits++
+	}
+	en.touchedAt = now
+	c.ll.MoveToFront(ele)
+	return en.value, staleFor, true
+}
+
+// SetStaleGrace задаёт, сколько просроченные записи ещё хранятся для
+// GetStale (0 — удаляются сразу по TTL). Вызывать до начала работы.
+func (c *LRU[V]) SetStaleGrace(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grace = d
+}
+
+// Peek возвращает значение и срок его жизни, не меняя порядок вытеснения
+// и счётчики. Запись, просроченная больше окна SetStaleGrace, считается
+// отсутствующей.
+func (c *LRU[V]) Peek(key string) (v V, expiresAt time.Time, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
 	if ele, found := c.items[key]; found {
 		en := ele.Value.(*entry[V])
-		if c.ttl > 0 && time.Now().After(en.expiresAt) {
-			c.removeElement(ele)
-			var zero V
-			return zero, false
+		if en.expiresAt.IsZero() || !time.Now().After(en.expiresAt.Add(c.grace)) {
+			return en.value, en.expiresAt, true
 		}
-		c.ll.MoveToFront(ele)
-		return en.value, true
 	}
 	var zero V
-	return zero, false
+	return zero, time.Time{}, false
 }
 
-func (c *LRU[V]) Set(key string, value V) {
+// Set кладёт значение. Если значение дороже MaxItemCost, оно не кладётся,
+// а прежнее значение по ключу удаляется, чтобы не отдавать устаревшее.
+func (c *LRU[V]) Set(key string, value V) { c.SetWithTTL(key, value, 0) }
+
+// SetWithTTL как Set, но со своим сроком жизни записи (ttl <= 0 — TTL кэша).
+func (c *LRU[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	var cost int64
+	if c.costFn != nil {
+		cost = c.costFn(value)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.maxItemCost > 0 && cost > c.maxItemCost {
+		if ele, found := c.items[key]; found {
+			c.removeElement(ele)
+		}
+		c.rejections++
+		return
+	}
+
+	now := time.Now()
 	if ele, found := c.items[key]; found {
 		en := ele.Value.(*entry[V])
 		en.value = value
-		if c.ttl > 0 {
-			en.expiresAt = time.Now().Add(c.ttl)
+		en.touchedAt = now
+		en.storedAt = now
+		c.cost += cost - en.cost
+		en.cost = cost
+		en.expiresAt = time.Time{}
+		if ttl > 0 {
+			en.expiresAt = now.Add(ttl)
 		}
 		c.ll.MoveToFront(ele)
-		return
-	}
-
-	en := &entry[V]{key: key, value: value}
-	if c.ttl > 0 {
-		en.expiresAt = time.Now().Add(c.ttl)
+	} else {
+		en := &entry[V]{key: key, value: value, touchedAt: now, storedAt: now, cost: cost}
+		if ttl > 0 {
+			en.expiresAt = now.Add(ttl)
+		}
+		c.items[key] = c.ll.PushFront(en)
+		c.cost += cost
 	}
-	ele := c.ll.PushFront(en)
-	c.items[key] = ele
 
-	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxCost > 0 && c.cost > c.maxCost) {
 		c.removeOldest()
 	}
 }
@@ -86,12 +208,41 @@ func (c *LRU[V]) Purge() {
 	defer c.mu.Unlock()
 	c.ll = list.New()
 	c.items = make(map[string]*list.Element)
+	c.cost = 0
+}
+
+// Stats возвращает счётчики и текущий размер. OldestAge ищется проходом
+// по всем записям (Get меняет порядок LRU, но не возраст), так что Stats —
+// для админки, а не для горячего пути.
+func (c *LRU[V]) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	st := Stats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		StaleHits:   c.staleHits,
+		Expirations: c.expirations,
+		Evictions:   c.evictions,
+		Rejections:  c.rejections,
+		Size:        c.ll.Len(),
+		Cost:        c.cost,
+	}
+	if ele := c.ll.Back(); ele != nil {
+		now := time.Now()
+		st.OldestIdle = now.Sub(ele.Value.(*entry[V]).touchedAt)
+		for _, e := range c.items {
+			st.OldestAge = max(st.OldestAge, now.Sub(e.Value.(*entry[V]).storedAt))
+		}
+	}
+	return st
 }
 
 func (c *LRU[V]) removeOldest() {
 	ele := c.ll.Back()
 	if ele != nil {
 		c.removeElement(ele)
+		c.evictions++
 	}
 }
 
@@ -99,4 +250,5 @@ func (c *LRU[V]) removeElement(e *list.Element) {
 	c.ll.Remove(e)
 	en := e.Value.(*entry[V])
 	delete(c.items, en.key)
+	c.cost -= en.cost
 }
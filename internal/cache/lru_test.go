@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUStats(t *testing.T) {
+	c := NewLRU[int](2, time.Hour)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")       // hit; b становится самой давней
+	c.Get("missing") // miss
+	c.Set("c", 3)    // вытесняет b
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b must be evicted")
+	}
+	st := c.Stats()
+	want := Stats{Hits: 1, Misses: 2, Evictions: 1, Size: 2}
+	st.OldestAge, st.OldestIdle = 0, 0
+	if st != want {
+		t.Fatalf("stats = %+v, want %+v", st, want)
+	}
+
+	// Peek не считается обращением
+	if v, _, ok := c.Peek("a"); !ok || v != 1 {
+		t.Fatalf("Peek(a) = %v, %v", v, ok)
+	}
+	if got := c.Stats().Hits; got != 1 {
+		t.Fatalf("hits after Peek = %d, want 1", got)
+	}
+}
+
+func TestLRUStatsExpiration(t *testing.T) {
+	c := NewLRU[int](10, time.Millisecond)
+	c.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a must be expired")
+	}
+	st := c.Stats()
+	if st.Expirations != 1 || st.Misses != 1 || st.Size != 0 {
+		t.Fatalf("stats = %+v, want 1 expiration, 1 miss, size 0", st)
+	}
+}
+
+func TestShardedSpreadsAndSumsStats(t *testing.T) {
+	c := NewSharded[int](4, 400, time.Hour) // с запасом: шарды заполняются неравномерно
+	for i := range 100 {
+		c.Set(strconv.Itoa(i), i)
+	}
+	for i := range 100 {
+		if v, ok := c.Get(strconv.Itoa(i)); !ok || v != i {
+			t.Fatalf("Get(%d) = %v, %v", i, v, ok)
+		}
+	}
+	st := c.Stats()
+	if st.Hits != 100 || st.Size != 100 {
+		t.Fatalf("stats = %+v, want 100 hits, size 100", st)
+	}
+
+	c.Delete("0")
+	if _, _, ok := c.Peek("0"); ok {
+		t.Fatal("0 must be deleted")
+	}
+	c.Purge()
+	if st := c.Stats(); st.Size != 0 {
+		t.Fatalf("size after Purge = %d", st.Size)
+	}
+}
+
+func TestLRUCostBudget(t *testing.T) {
+	c := NewLRUWithCost(0, time.Hour, CostOptions[string]{
+		Cost:        func(v string) int64 { return int64(len(v)) },
+		MaxCost:     10,
+		MaxItemCost: 6,
+	})
+	c.Set("a", "aaaa")     // 4
+	c.Set("b", "bbbb")     // 8
+	c.Set("c", "cccc")     // 12 > 10: вытесняется a
+	c.Set("d", "dddddddd") // 8 > 6: отклоняется
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a must be evicted by budget")
+	}
+	if _, ok := c.Get("d"); ok {
+		t.Fatal("d must be rejected as too expensive")
+	}
+	st := c.Stats()
+	if st.Cost != 8 || st.Size != 2 || st.Evictions != 1 || st.Rejections != 1 {
+		t.Fatalf("stats = %+v, want cost 8, size 2, 1 eviction, 1 rejection", st)
+	}
+
+	// слишком дорогое новое значение убирает прежнее
+	c.Set("b", "bbbbbbbbbb")
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b must be dropped after rejected update")
+	}
+	if st := c.Stats(); st.Cost != 4 {
+		t.Fatalf("cost after rejected update = %d, want 4", st.Cost)
+	}
+}
+
+func TestLRUStaleGrace(t *testing.T) {
+	c := NewLRU[int](10, time.Millisecond)
+	c.SetStaleGrace(time.Hour)
+	c.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get must not return an expired value")
+	}
+	v, staleFor, ok := c.GetStale("a")
+	if !ok || v != 1 || staleFor <= 0 {
+		t.Fatalf("GetStale = %v, %v, %v; want stale 1", v, staleFor, ok)
+	}
+	if st := c.Stats(); st.StaleHits != 1 || st.Expirations != 0 || st.Size != 1 {
+		t.Fatalf("stats = %+v, want 1 stale hit, entry kept", st)
+	}
+
+	c.Set("a", 2)
+	if v, staleFor, ok := c.GetStale("a"); !ok || v != 2 || staleFor != 0 {
+		t.Fatalf("GetStale after Set = %v, %v, %v; want fresh 2", v, staleFor, ok)
+	}
+}
+
+func TestLRUSetWithTTL(t *testing.T) {
+	c := NewLRU[int](10, time.Hour)
+	c.SetWithTTL("short", 1, time.Millisecond)
+	c.Set("long", 2)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("short must expire by its own TTL")
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Fatal("long must live by the cache TTL")
+	}
+
+	// Set поверх короткой записи возвращает TTL кэша
+	c.SetWithTTL("k", 1, time.Millisecond)
+	c.Set("k", 2)
+	time.Sleep(5 * time.Millisecond)
+	if v, ok := c.Get("k"); !ok || v != 2 {
+		t.Fatalf("Get(k) = %v, %v; want 2 with cache TTL", v, ok)
+	}
+}
+
+func TestLRUStatsOldestAge(t *testing.T) {
+	c := NewLRU[int](10, time.Hour)
+	c.Set("old", 1)
+	time.Sleep(20 * time.Millisecond)
+	c.Set("new", 2)
+	c.Get("old") // чтение не молодит запись, но делает "new" самой давней по LRU
+
+	st := c.Stats()
+	if st.OldestAge < 20*time.Millisecond {
+		t.Fatalf("OldestAge = %v, want age of \"old\" (>= 20ms)", st.OldestAge)
+	}
+	if st.OldestIdle >= 20*time.Millisecond {
+		t.Fatalf("OldestIdle = %v, want idle time of \"new\" (< 20ms)", st.OldestIdle)
+	}
+}
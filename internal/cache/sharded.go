@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+// Cache — общий API LRU и Sharded.
+type Cache[V any] interface {
+	Get(key string) (V, bool)
+	GetStale(key string) (V, time.Duration, bool)
+	SetStaleGrace(d time.Duration)
+	Peek(key string) (V, time.Time, bool)
+	Set(key string, value V)
+	SetWithTTL(key string, value V, ttl time.Duration)
+	Delete(key string)
+	Purge()
+	Stats() Stats
+}
+
+var (
+	_ Cache[int] = (*LRU[int])(nil)
+	_ Cache[int] = (*Sharded[int])(nil)
+)
+
+// Sharded — n независимых LRU, шард выбирается по хэшу ключа. Get на LRU
+// берёт эксклюзивную блокировку (двигает элемент в списке), так что один
+// LRU сериализует всех читателей; шарды снимают эту конкуренцию.
+// Порядок вытеснения — LRU внутри шарда, а не глобально.
+type Sharded[V any] struct {
+	seed   maphash.Seed
+	shards []*LRU[V]
+}
+
+// NewSharded делит maxEntries поровну (с округлением вверх) между shards шардами.
+// TTL — как у LRU.
+func NewSharded[V any](shards, maxEntries int, ttl time.Duration) *Sharded[V] {
+	return NewShardedWithCost(shards, maxEntries, ttl, CostOptions[V]{})
+}
+
+// NewShardedWithCost — Sharded из LRU с бюджетом: maxEntries и o.MaxCost
+// делятся между шардами, MaxItemCost не больше бюджета шарда.
+func NewShardedWithCost[V any](shards, maxEntries int, ttl time.Duration, o CostOptions[V]) *Sharded[V] {
+	if shards < 1 {
+		shards = 1
+	}
+	per := 0
+	if maxEntries > 0 {
+		per = (maxEntries + shards - 1) / shards
+	}
+	if o.MaxCost > 0 {
+		o.MaxCost = (o.MaxCost + int64(shards) - 1) / int64(shards)
+	}
+	c := &Sharded[V]{seed: maphash.MakeSeed(), shards: make([]*LRU[V], shards)}
+	for i := range c.shards {
+		c.shards[i] = NewLRUWithCost(per, ttl, o)
+	}
+	return c
+}
+
+func (c *Sharded[V]) shard(key string) *LRU[V] {
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+func (c *Sharded[V]) Get(key string) (V, bool) { return c.shard(key).Get(key) }
+
+func (c *Sharded[V]) GetStale(key string) (V, time.Duration, bool) { return c.shard(key).GetStale(key) }
+
+func (c *Sharded[V]) SetStaleGrace(d time.Duration) {
+	for _, s := range c.shards {
+		s.SetStaleGrace(d)
+	}
+}
+
+func (c *Sharded[V]) Peek(key string) (V, time.Time, bool) { return c.shard(key).Peek(key) }
+
+func (c *Sharded[V]) Set(key string, value V) { c.shard(key).Set(key, value) }
+
+func (c *Sharded[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	c.shard(key).SetWithTTL(key, value, ttl)
+}
+
+func (c *Sharded[V]) Delete(key string) { c.shard(key).Delete(key) }
+
+func (c *Sharded[V]) Purge() {
+	for _, s := range c.shards {
+		s.Purge()
+	}
+}
+
+// Stats суммирует счётчики шардов; OldestAge и OldestIdle — максимальные по шардам.
+func (c *Sharded[V]) Stats() Stats {
+	var st Stats
+	for _, s := range c.shards {
+		ss := s.Stats()
+		st.Hits += ss.Hits
+		st.Misses += ss.Misses
+		st.StaleHits += ss.StaleHits
+		st.Expirations += ss.Expirations
+		st.Evictions += ss.Evictions
+		st.Rejections += ss.Rejections
+		st.Size += ss.Size
+		st.Cost += ss.Cost
+		st.OldestAge = max(st.OldestAge, ss.OldestAge)
+		st.OldestIdle = max(st.OldestIdle, ss.OldestIdle)
+	}
+	return st
+}
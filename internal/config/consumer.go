@@ -1,6 +1,11 @@
 package config
 
-import "log/slog"
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
 
 type ConsumerConfig struct {
 	AppEnv       Env
@@ -8,30 +13,120 @@ type ConsumerConfig struct {
 	KafkaTopic   string
 	KafkaGroupID string
 	DLQTopic     string
+	DLQBackend   string // kafka|postgres|noop
 	PostgresDSN  string
 	LogFormat    string
 	LogLevel     string
+
+	Workers  int    // 1 — последовательная обработка, >1 — пул воркеров
+	Dispatch string // partition|key — как раскладывать сообщения по воркерам
+
+	BatchSize   int           // >1 — микробатчинг: SaveOrders на пачку сообщений
+	BatchLinger time.Duration // максимальное ожидание добора пачки
+
+	RetryTiers       []RetryTier // пусто — ретраи выключены
+	RetryMaxAttempts int         // всего попыток, включая первую
+
+	StaleToDLQ bool // устаревшие версии заказов — в DLQ с причиной stale_update
+
+	InstanceID string // ID экземпляра для конвертов DLQ, по умолчанию hostname
+}
+
+// RetryTier — топик ретраев и задержка перед повторной обработкой.
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
 }
 
 func LoadConsumer() ConsumerConfig {
 	env := Env(get("APP_ENV", "local"))
 	b := selectBootstrap(env)
 
+	workers, ok1 := atoiDefault(get("CONSUMER_WORKERS", "1"), 1)
+	if !ok1 || workers < 1 {
+		slog.Warn("config: bad CONSUMER_WORKERS, use 1")
+		workers = 1
+	}
+	dispatch := get("CONSUMER_DISPATCH", "partition")
+	if dispatch != "partition" && dispatch != "key" {
+		slog.Warn("config: bad CONSUMER_DISPATCH, use partition")
+		dispatch = "partition"
+	}
+
+	batchSize, ok2 := atoiDefault(get("CONSUMER_BATCH_SIZE", "1"), 1)
+	if !ok2 || batchSize < 1 {
+		slog.Warn("config: bad CONSUMER_BATCH_SIZE, use 1")
+		batchSize = 1
+	}
+	batchLinger, ok3 := durDefault(get("CONSUMER_BATCH_LINGER", "100ms"), 100*time.Millisecond)
+	if !ok3 {
+		slog.Warn("config: bad CONSUMER_BATCH_LINGER, use 100ms")
+	}
+
+	tiers, ok4 := parseRetryTiers(get("KAFKA_RETRY_TOPICS", ""))
+	if !ok4 {
+		slog.Warn("config: bad KAFKA_RETRY_TOPICS (want topic:delay,...), retries disabled")
+	}
+	maxAttempts, ok5 := atoiDefault(get("RETRY_MAX_ATTEMPTS", "3"), 3)
+	if !ok5 || maxAttempts < 1 {
+		slog.Warn("config: bad RETRY_MAX_ATTEMPTS, use 3")
+		maxAttempts = 3
+	}
+
+	dlqBackend := get("DLQ_BACKEND", "kafka")
+	if dlqBackend != "kafka" && dlqBackend != "postgres" && dlqBackend != "noop" {
+		slog.Warn("config: bad DLQ_BACKEND, use kafka")
+		dlqBackend = "kafka"
+	}
+
+	host, _ := os.Hostname()
+
 	cfg := ConsumerConfig{
 		AppEnv:       env,
 		KafkaBrokers: b.Brokers,
 		KafkaTopic:   get("KAFKA_TOPIC", "orders"),
 		KafkaGroupID: get("KAFKA_GROUP_ID", "orders-consumer"),
 		DLQTopic:     get("KAFKA_DLQ_TOPIC", ""),
+		DLQBackend:   dlqBackend,
 		PostgresDSN:  b.DSN,
 		LogFormat:    get("LOG_FORMAT", "text"),
 		LogLevel:     get("LOG_LEVEL", "INFO"),
+		Workers:      workers,
+		Dispatch:     dispatch,
+		BatchSize:    batchSize,
+		BatchLinger:  batchLinger,
+
+		RetryTiers:       tiers,
+		RetryMaxAttempts: maxAttempts,
+
+		StaleToDLQ: get("STALE_TO_DLQ", "false") == "true",
+		InstanceID: get("INSTANCE_ID", host),
 	}
 	if len(cfg.KafkaBrokers) == 0 {
 		slog.Warn("config: empty Kafka bootstrap")
 	}
+	if cfg.BatchSize > 1 && cfg.Workers > 1 {
+		slog.Warn("config: CONSUMER_BATCH_SIZE and CONSUMER_WORKERS are exclusive, batching wins")
+	}
 	if cfg.PostgresDSN == "" {
 		slog.Warn("config: empty Postgres DSN")
 	}
 	return cfg
 }
+
+// parseRetryTiers разбирает "orders-retry-5s:5s,orders-retry-1m:1m".
+func parseRetryTiers(s string) ([]RetryTier, bool) {
+	var tiers []RetryTier
+	for _, p := range splitCSV(s) {
+		topic, delay, found := strings.Cut(p, ":")
+		if !found {
+			return nil, false
+		}
+		d, ok := durDefault(strings.TrimSpace(delay), 0)
+		if !ok || d <= 0 || strings.TrimSpace(topic) == "" {
+			return nil, false
+		}
+		tiers = append(tiers, RetryTier{Topic: strings.TrimSpace(topic), Delay: d})
+	}
+	return tiers, true
+}
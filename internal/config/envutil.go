@@ -45,6 +45,29 @@ func durDefault(s string, def time.Duration) (time.Duration, bool) {
 	return d, err == nil
 }
 
+// bytesDefault разбирает размер: число байт или с суффиксом KB/MB/GB (по 1024).
+func bytesDefault(s string, def int64) (int64, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return def, true
+	}
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}} {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, mult = strings.TrimSpace(v), u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return def, false
+	}
+	return v * mult, true
+}
+
 func floatDefault(s string, def float64) (float64, bool) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -0,0 +1,25 @@
+package config
+
+type ReplayConfig struct {
+	AppEnv       Env
+	KafkaBrokers []string
+	DLQTopic     string
+	PostgresDSN  string
+	LogFormat    string
+	LogLevel     string
+}
+
+// LoadReplay читает настройки утилиты dlq-replay; фильтры задаются флагами.
+func LoadReplay() ReplayConfig {
+	env := Env(get("APP_ENV", "local"))
+	b := selectBootstrap(env)
+
+	return ReplayConfig{
+		AppEnv:       env,
+		KafkaBrokers: b.Brokers,
+		DLQTopic:     get("KAFKA_DLQ_TOPIC", "orders-dlq"),
+		PostgresDSN:  b.DSN,
+		LogFormat:    get("LOG_FORMAT", "text"),
+		LogLevel:     get("LOG_LEVEL", "INFO"),
+	}
+}
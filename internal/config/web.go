@@ -2,6 +2,7 @@ package config
 
 import (
 	"log/slog"
+	"os"
 	"time"
 )
 
@@ -12,14 +13,47 @@ type WebConfig struct {
 	LogFormat   string
 	LogLevel    string
 
-	CacheSize int
-	CacheTTL  time.Duration
+	CacheSize   int
+	CacheTTL    time.Duration
+	CacheShards int // число шардов кэша; 1 — один LRU
+
+	// Отдача просроченных записей: сразу с обновлением в фоне / при ошибке БД
+	CacheStaleWhileRevalidate time.Duration
+	CacheStaleIfError         time.Duration
+
+	CacheMaxBytes     int64 // бюджет кэша в байтах (0 — только CACHE_SIZE)
+	CacheMaxItemBytes int64 // заказы крупнее не кэшируются
+
+	// Негативный кэш для несуществующих id (TTL 0 — выключен)
+	NegativeCacheSize int
+	NegativeCacheTTL  time.Duration
+
+	CacheWarmup        int // сколько последних заказов загрузить при старте (0 — не прогревать)
+	CacheWarmupTimeout time.Duration
+
+	CacheListen bool // сбрасывать кэш по NOTIFY orders_changed из БД
+
+	// Наполнение кэша прямо из топика заказов, своей группой на экземпляр
+	CacheFeed    bool
+	CacheFeedTTL time.Duration // сколько живут заказы из топика, пока их не заменит версия из БД
+	KafkaBrokers []string
+	KafkaTopic   string
+	FeedGroupID  string
+
+	CacheControl string // Cache-Control для ответов /order/{id}
+
+	AdminToken string // токен для /admin/*; пусто — админка выключена
+
+	DeadLettersAPI bool // /api/v1/dead-letters поверх таблицы dead_letters
 
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 }
 
+// minWarmupTTL — TTL, начиная с которого прогрев кэша имеет смысл.
+const minWarmupTTL = 5 * time.Minute
+
 // LoadWeb читает настройки веб-приложения из окружения.
 func LoadWeb() WebConfig {
 	env := Env(get("APP_ENV", "local"))
@@ -46,6 +80,59 @@ func LoadWeb() WebConfig {
 		slog.Warn("config: bad CACHE_TTL, fallback to 30s")
 	}
 
+	cacheShards, ok10 := atoiDefault(get("CACHE_SHARDS", "1"), 1)
+	if !ok10 || cacheShards < 1 {
+		slog.Warn("config: bad CACHE_SHARDS, fallback to 1")
+		cacheShards = 1
+	}
+
+	swr, ok13 := durDefault(get("CACHE_STALE_WHILE_REVALIDATE", "10s"), 10*time.Second)
+	if !ok13 {
+		slog.Warn("config: bad CACHE_STALE_WHILE_REVALIDATE, fallback to 10s")
+	}
+	sie, ok14 := durDefault(get("CACHE_STALE_IF_ERROR", "10m"), 10*time.Minute)
+	if !ok14 {
+		slog.Warn("config: bad CACHE_STALE_IF_ERROR, fallback to 10m")
+	}
+
+	maxBytes, ok11 := bytesDefault(get("CACHE_MAX_BYTES", "0"), 0)
+	if !ok11 {
+		slog.Warn("config: bad CACHE_MAX_BYTES, byte budget disabled")
+	}
+	maxItemBytes, ok12 := bytesDefault(get("CACHE_MAX_ITEM_BYTES", "0"), 0)
+	if !ok12 {
+		slog.Warn("config: bad CACHE_MAX_ITEM_BYTES, fallback to 0")
+	}
+
+	negSize, ok8 := atoiDefault(get("NEGATIVE_CACHE_SIZE", "10000"), 10000)
+	if !ok8 {
+		slog.Warn("config: bad NEGATIVE_CACHE_SIZE, fallback to 10000")
+	}
+	negTTL, ok9 := durDefault(get("NEGATIVE_CACHE_TTL", "5s"), 5*time.Second)
+	if !ok9 {
+		slog.Warn("config: bad NEGATIVE_CACHE_TTL, fallback to 5s")
+	}
+
+	// Группа читателя топика — своя у каждого экземпляра: кэш у каждого свой
+	host, _ := os.Hostname()
+	feedGroup := get("CACHE_FEED_GROUP", "orders-web") + "-" + get("INSTANCE_ID", host)
+
+	feedTTL, ok15 := durDefault(get("CACHE_FEED_TTL", "30s"), 30*time.Second)
+	if !ok15 {
+		slog.Warn("config: bad CACHE_FEED_TTL, fallback to 30s")
+	}
+
+	// Прогрев кэша
+	warmup, ok6 := atoiDefault(get("CACHE_WARMUP", "1000"), 1000)
+	if !ok6 || warmup < 0 {
+		slog.Warn("config: bad CACHE_WARMUP, fallback to 1000")
+		warmup = 1000
+	}
+	warmupTO, ok7 := durDefault(get("CACHE_WARMUP_TIMEOUT", "30s"), 30*time.Second)
+	if !ok7 {
+		slog.Warn("config: bad CACHE_WARMUP_TIMEOUT, fallback to 30s")
+	}
+
 	// Таймауты HTTP
 	readTO, ok3 := durDefault(get("HTTP_READ_TIMEOUT", "10s"), 10*time.Second)
 	if !ok3 {
@@ -61,22 +148,60 @@ func LoadWeb() WebConfig {
 	}
 
 	cfg := WebConfig{
-		AppEnv:       env,
-		Addr:         addr,
-		PostgresDSN:  dsn,
-		LogFormat:    get("LOG_FORMAT", "text"),
-		LogLevel:     get("LOG_LEVEL", "INFO"),
-		CacheSize:    cacheSize,
-		CacheTTL:     cacheTTL,
-		ReadTimeout:  readTO,
-		WriteTimeout: writeTO,
-		IdleTimeout:  idleTO,
+		AppEnv:                    env,
+		Addr:                      addr,
+		PostgresDSN:               dsn,
+		LogFormat:                 get("LOG_FORMAT", "text"),
+		LogLevel:                  get("LOG_LEVEL", "INFO"),
+		CacheSize:                 cacheSize,
+		CacheTTL:                  cacheTTL,
+		CacheShards:               cacheShards,
+		CacheStaleWhileRevalidate: swr,
+		CacheStaleIfError:         sie,
+		CacheMaxBytes:             maxBytes,
+		CacheMaxItemBytes:         maxItemBytes,
+		NegativeCacheSize:         negSize,
+		NegativeCacheTTL:          negTTL,
+		CacheWarmup:               warmup,
+		CacheWarmupTimeout:        warmupTO,
+		CacheListen:               get("CACHE_LISTEN", "true") == "true",
+		CacheFeed:                 get("CACHE_FEED", "false") == "true",
+		CacheFeedTTL:              feedTTL,
+		KafkaBrokers:              selectBootstrap(env).Brokers,
+		KafkaTopic:                get("KAFKA_TOPIC", "orders"),
+		FeedGroupID:               feedGroup,
+		CacheControl:              get("HTTP_CACHE_CONTROL", "no-cache"),
+		AdminToken:                get("ADMIN_TOKEN", ""),
+		DeadLettersAPI:            get("DLQ_API", "false") == "true",
+		ReadTimeout:               readTO,
+		WriteTimeout:              writeTO,
+		IdleTimeout:               idleTO,
 	}
 
 	// Лёгкие предупреждения
 	if cfg.PostgresDSN == "" {
 		slog.Warn("config: empty Postgres DSN", slog.String("APP_ENV", string(env)))
 	}
+	// прогретые записи живут CACHE_TTL: с коротким TTL прогрев пропадает
+	// раньше, чем до заказов дойдут первые запросы
+	if cfg.CacheWarmup > 0 && cfg.CacheTTL < minWarmupTTL {
+		slog.Warn("config: CACHE_WARMUP with short CACHE_TTL, warmed orders expire before they are requested",
+			slog.Duration("CACHE_TTL", cfg.CacheTTL),
+			slog.Duration("recommended_min", minWarmupTTL),
+		)
+	}
+	if cfg.CacheFeed && len(cfg.KafkaBrokers) == 0 {
+		slog.Warn("config: CACHE_FEED enabled but Kafka bootstrap is empty, feed disabled")
+		cfg.CacheFeed = false
+	}
+	// отметку «нет заказа» снимают только NOTIFY и поток заказов: без них
+	// заказ, записанный после 404, отдавал бы 404 весь NEGATIVE_CACHE_TTL
+	if cfg.NegativeCacheTTL > 0 && !cfg.CacheListen && !cfg.CacheFeed {
+		slog.Warn("config: NEGATIVE_CACHE_TTL needs CACHE_LISTEN or CACHE_FEED to clear misses on ingest, negative cache disabled",
+			slog.Duration("NEGATIVE_CACHE_TTL", cfg.NegativeCacheTTL),
+		)
+		cfg.NegativeCacheTTL = 0
+	}
 	if cfg.Addr == "" {
 		slog.Warn("config: empty HTTP_ADDR, fallback to :4000")
 		cfg.Addr = ":4000"
@@ -0,0 +1,183 @@
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+type BatchConfig struct {
+	MaxSize   int           // сбрасываем пачку, набрав столько сообщений
+	MaxLinger time.Duration // или спустя столько времени после первого сообщения
+}
+
+// Batcher копит валидные заказы и сохраняет их одной транзакцией SaveOrders.
+// Смещения коммитятся только после коммита транзакции.
+type Batcher struct {
+	h   *Handler
+	src Source
+	cfg BatchConfig
+	log *slog.Logger
+}
+
+func NewBatcher(h *Handler, src Source, cfg BatchConfig, log *slog.Logger) *Batcher {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100
+	}
+	if cfg.MaxLinger <= 0 {
+		cfg.MaxLinger = 100 * time.Millisecond
+	}
+	return &Batcher{h: h, src: src, cfg: cfg, log: log}
+}
+
+type batchEntry struct {
+	msg    kafka.Message
+	order  domain.Order
+	valid  bool // заказ нужно сохранить
+	commit bool // смещение можно коммитить
+}
+
+// Run читает сообщения до отмены ctx; накопленная пачка при остановке сохраняется.
+func (b *Batcher) Run(ctx context.Context) {
+	msgs := make(chan kafka.Message)
+	go func() {
+		defer close(msgs)
+		for {
+			msg, err := b.src.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					b.log.Info("shutdown: stop reading, flushing batch")
+					return
+				}
+				b.log.Error("kafka read failed", slog.Any("err", err))
+				time.Sleep(time.Second)
+				continue
+			}
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// обработка не прерывается по ctx — последнюю пачку дописываем
+	workCtx := context.WithoutCancel(ctx)
+
+	var (
+		batch  []batchEntry
+		timer  *time.Timer
+		linger <-chan time.Time
+	)
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, linger = nil, nil
+		}
+		if len(batch) > 0 {
+			b.flush(workCtx, batch)
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				flush()
+				return
+			}
+			order, valid, commit := b.h.prepare(workCtx, msg)
+			batch = append(batch, batchEntry{msg: msg, order: order, valid: valid, commit: commit})
+			if len(batch) == 1 {
+				timer = time.NewTimer(b.cfg.MaxLinger)
+				linger = timer.C
+			}
+			if len(batch) >= b.cfg.MaxSize {
+				flush()
+			}
+		case <-linger:
+			flush()
+		}
+	}
+}
+
+// flush сохраняет пачку и коммитит смещения. Если транзакция пачки не прошла,
+// заказы сохраняются по одному, чтобы один плохой заказ не тормозил остальные.
+func (b *Batcher) flush(ctx context.Context, batch []batchEntry) {
+	recs := make([]storage.Record, 0, len(batch))
+	owners := make([]int, 0, len(batch)) // recs[i] принадлежит batch[owners[i]]
+	for i, e := range batch {
+		if e.valid {
+			recs = append(recs, b.h.record(e.msg, e.order))
+			owners = append(owners, i)
+		}
+	}
+
+	if len(recs) > 0 {
+		start := time.Now()
+		dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		res, err := b.h.store.SaveOrders(dbCtx, recs)
+		cancel()
+
+		if err == nil {
+			b.log.Debug("batch saved",
+				slog.Int("orders", res.Saved),
+				slog.Int("duplicates", res.Duplicates),
+				slog.Int("stale", len(res.Stale)),
+				slog.Int("messages", len(batch)),
+				slog.Duration("dur", time.Since(start)),
+			)
+			for i := range batch {
+				if batch[i].valid {
+					batch[i].commit = true
+				}
+			}
+			for _, i := range res.Stale {
+				e := &batch[owners[i]]
+				e.commit = b.h.skipStale(e.msg, MsgLogger(b.log, e.msg))
+			}
+		} else {
+			b.log.Warn("batch save failed, falling back to single saves",
+				slog.Int("orders", len(recs)),
+				slog.Any("err", err),
+			)
+			for i := range batch {
+				if batch[i].valid {
+					batch[i].commit = b.h.save(ctx, batch[i].msg, batch[i].order)
+				}
+			}
+		}
+	}
+
+	// по одному смещению на партицию — последнему, которое можно коммитить
+	last := make(map[partitionKey]kafka.Message)
+	for _, e := range batch {
+		if !e.commit {
+			continue
+		}
+		k := partitionKey{topic: e.msg.Topic, partition: e.msg.Partition}
+		if m, ok := last[k]; !ok || e.msg.Offset > m.Offset {
+			last[k] = e.msg
+		}
+	}
+	if len(last) == 0 {
+		return
+	}
+	msgs := make([]kafka.Message, 0, len(last))
+	for _, m := range last {
+		msgs = append(msgs, m)
+	}
+
+	commitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.src.CommitMessages(commitCtx, msgs...); err != nil {
+		b.log.Error("batch commit failed; messages will be reprocessed", slog.Any("err", err))
+		return
+	}
+	b.log.Debug("batch offsets commited", slog.Int("partitions", len(msgs)))
+}
@@ -2,50 +2,146 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"github.com/sillkiw/wb-l0/internal/dlq"
 	"github.com/sillkiw/wb-l0/internal/domain"
 	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/storage"
 	"github.com/sillkiw/wb-l0/internal/validation"
 )
 
 type Store interface {
-	SaveOrder(ctx context.Context, order domain.Order) error
+	SaveOrder(ctx context.Context, rec storage.Record) error
+	SaveOrders(ctx context.Context, recs []storage.Record) (storage.SaveResult, error)
 }
 
 type DLQ interface {
-	Send(ctx context.Context, m kafka.Message, reason string, attempt int) error
+	Send(ctx context.Context, m kafka.Message, f dlq.Failure) error
+}
+
+// Retrier откладывает сообщение для повторной обработки (топики ретраев).
+type Retrier interface {
+	Send(ctx context.Context, m kafka.Message, attempt int) error
+}
+
+type Options struct {
+	Retry       Retrier // nil — ретраи выключены
+	MaxAttempts int     // всего попыток, включая первую; после них — DLQ
+
+	// Журнал обработанных сообщений ведётся для сообщений топика Topic группы GroupID.
+	GroupID string
+	Topic   string
+
+	Instance string // хост/ID экземпляра, пишется в конверт DLQ
+
+	StaleToDLQ bool // отправлять устаревшие версии заказов в DLQ (stale_update)
 }
 
 type Handler struct {
 	store Store
 	dlq   DLQ
 	log   *slog.Logger
+
+	retry       Retrier
+	maxAttempts int
+
+	groupID  string
+	topic    string
+	instance string
+
+	staleToDLQ bool
+	stale      atomic.Int64 // сколько устаревших версий пропущено
+}
+
+func NewHandler(store Store, dlq DLQ, log *slog.Logger, opts Options) *Handler {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	return &Handler{
+		store:       store,
+		dlq:         dlq,
+		log:         log,
+		retry:       opts.Retry,
+		maxAttempts: opts.MaxAttempts,
+		groupID:     opts.GroupID,
+		topic:       opts.Topic,
+		instance:    opts.Instance,
+		staleToDLQ:  opts.StaleToDLQ,
+	}
+}
+
+// StaleCount — число сообщений, пропущенных как устаревшие версии заказа.
+func (h *Handler) StaleCount() int64 { return h.stale.Load() }
+
+// record собирает запись для storage; версия — время исходного сообщения,
+// источник — координаты исходного сообщения (и для ретраев тоже).
+func (h *Handler) record(msg kafka.Message, order domain.Order) storage.Record {
+	topic, partition, offset := msg.Origin()
+	return storage.Record{
+		Order:   order,
+		Origin:  h.origin(msg),
+		Source:  storage.Source{Topic: topic, Partition: partition, Offset: offset, Key: string(msg.Key)},
+		Version: msg.OriginTimestamp(),
+	}
+}
+
+// origin — координаты сообщения для журнала. Для сообщений из топиков
+// ретраев журнал не ведётся: их повтор перекрывает идемпотентный UPSERT.
+func (h *Handler) origin(msg kafka.Message) storage.Origin {
+	if h.groupID == "" || msg.Topic != h.topic {
+		return storage.Origin{}
+	}
+	return storage.Origin{
+		GroupID:   h.groupID,
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	}
 }
 
-func NewHandler(store Store, dlq DLQ, log *slog.Logger) *Handler {
-	return &Handler{store: store, dlq: dlq, log: log}
+// failure — описание отказа для DLQ с общими полями сообщения и экземпляра.
+func (h *Handler) failure(msg kafka.Message, reason string) dlq.Failure {
+	return dlq.Failure{
+		Reason:        reason,
+		Attempt:       msg.Attempt(),
+		GroupID:       h.groupID,
+		Instance:      h.instance,
+		FirstFailedAt: msg.FirstFailedAt(),
+	}
 }
 
 func (h *Handler) Handle(ctx context.Context, msg kafka.Message) bool {
-	log := MsgLogger(h.log, msg)
+	order, ok, commit := h.prepare(ctx, msg)
+	if !ok {
+		return commit
+	}
+	return h.save(ctx, msg, order)
+}
 
-	var order domain.Order
+// prepare декодирует и валидирует сообщение. ok=false — заказ непригоден,
+// сообщение уже обработано (DLQ), commit говорит, можно ли коммитить смещение.
+func (h *Handler) prepare(_ context.Context, msg kafka.Message) (order domain.Order, ok bool, commit bool) {
+	log := MsgLogger(h.log, msg)
 	log.Debug("message")
 
 	// строгий парсинг
 	if err := validation.DecodeStrict(msg.Value, &order); err != nil {
 		log.Warn("json decode failed", slog.Any("err", err))
 		if h.dlq != nil {
-			if err2 := h.dlq.Send(context.Background(), msg, "unmarshal_failed", 1); err2 != nil {
+			f := h.failure(msg, "unmarshal_failed")
+			f.DecodeError = err.Error()
+			if err2 := h.dlq.Send(context.Background(), msg, f); err2 != nil {
 				log.Error("dlq send failed", slog.Any("err", err2))
-				return false // DLQ временно недоступен - ретраим
+				return order, false, false // DLQ временно недоступен - ретраим
 			}
 			log.Debug("sent to DLQ", slog.String("reason", "unmarshal_failed"))
-			return true // коммитим, чтобы не зациклиться
+			return order, false, true // коммитим, чтобы не зациклиться
 		}
-		return false // без DLQ - ретраим
+		return order, false, false // без DLQ - ретраим
 	}
 
 	// валидация
@@ -55,23 +151,110 @@ func (h *Handler) Handle(ctx context.Context, msg kafka.Message) bool {
 			slog.String("summary", verr.Error()),
 		)
 		if h.dlq != nil {
-			if err2 := h.dlq.Send(context.Background(), msg, "validation_failed", 1); err2 != nil {
+			f := h.failure(msg, "validation_failed")
+			f.Errors = verr.Fields
+			if err2 := h.dlq.Send(context.Background(), msg, f); err2 != nil {
 				log.Error("dlq send failed", slog.Any("err", err2))
-				return false
+				return order, false, false
 			}
 			log.Debug("sent to DLQ", slog.String("reason", "validation_failed"))
-			return true
+			return order, false, true
 		}
-		return false
+		return order, false, false
 	}
 
+	return order, true, false
+}
+
+// save сохраняет один валидный заказ.
+func (h *Handler) save(_ context.Context, msg kafka.Message, order domain.Order) bool {
+	log := MsgLogger(h.log, msg)
+
 	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := h.store.SaveOrder(dbCtx, order); err != nil {
-		log.Error("save failed", slog.Any("err", err))
-		return false
+	if err := h.store.SaveOrder(dbCtx, h.record(msg, order)); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			log.Info("message already processed, skip")
+			return true
+		}
+		if errors.Is(err, storage.ErrStale) {
+			return h.skipStale(msg, log)
+		}
+		var dbErr *storage.DBError
+		if errors.As(err, &dbErr) && storage.IsPermanent(err) {
+			log.Warn("save rejected by db", slog.Any("err", err))
+			return h.reject(msg, dbErr, log)
+		}
+		log.Error("save failed", slog.Any("err", err), slog.Int("attempt", msg.Attempt()))
+		return h.retryLater(msg, log)
 	}
 	log.Debug("order saved")
 
 	return true
 }
+
+// skipStale учитывает сообщение с устаревшей версией заказа и, если включено,
+// отправляет его в DLQ. Более новое состояние в БД при этом не трогается.
+func (h *Handler) skipStale(msg kafka.Message, log *slog.Logger) bool {
+	n := h.stale.Add(1)
+	log.Warn("stale order version skipped",
+		slog.Time("version", msg.OriginTimestamp()),
+		slog.Int64("stale_total", n),
+	)
+	if !h.staleToDLQ || h.dlq == nil {
+		return true
+	}
+	if err := h.dlq.Send(context.Background(), msg, h.failure(msg, "stale_update")); err != nil {
+		log.Error("dlq send failed", slog.Any("err", err))
+		return false
+	}
+	log.Debug("sent to DLQ", slog.String("reason", "stale_update"))
+	return true
+}
+
+// reject отправляет в DLQ заказ, который БД не примет ни при какой попытке.
+func (h *Handler) reject(msg kafka.Message, dbErr *storage.DBError, log *slog.Logger) bool {
+	if h.dlq == nil {
+		return false
+	}
+	f := h.failure(msg, "db_data_exception")
+	f.Detail = dbErr.Code
+	if dbErr.IntegrityViolation() {
+		f.Reason, f.Detail = "db_constraint_violation", dbErr.Constraint
+	}
+	if err := h.dlq.Send(context.Background(), msg, f); err != nil {
+		log.Error("dlq send failed", slog.Any("err", err))
+		return false
+	}
+	log.Debug("sent to DLQ", slog.String("reason", f.Reason), slog.String("detail", f.Detail))
+	return true
+}
+
+// retryLater отправляет сообщение на следующую ступень ретраев,
+// а исчерпав попытки — в DLQ. true — смещение можно коммитить.
+func (h *Handler) retryLater(msg kafka.Message, log *slog.Logger) bool {
+	if h.retry == nil {
+		return false // без ретраев - сообщение перечитается после рестарта/ребаланса
+	}
+
+	attempt := msg.Attempt()
+	if attempt < h.maxAttempts {
+		if err := h.retry.Send(context.Background(), msg, attempt+1); err != nil {
+			log.Error("retry send failed", slog.Any("err", err))
+			return false
+		}
+		log.Info("scheduled for retry", slog.Int("next_attempt", attempt+1))
+		return true
+	}
+
+	if h.dlq == nil {
+		log.Error("retries exhausted, DLQ disabled", slog.Int("attempt", attempt))
+		return false
+	}
+	if err := h.dlq.Send(context.Background(), msg, h.failure(msg, "save_failed")); err != nil {
+		log.Error("dlq send failed", slog.Any("err", err))
+		return false
+	}
+	log.Warn("retries exhausted, sent to DLQ", slog.Int("attempt", attempt))
+	return true
+}
@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// Ledger — позиции чтения группы, хранимые в БД.
+type Ledger interface {
+	StoreOffsets(ctx context.Context, positions []storage.Origin) error
+}
+
+// WithLedger оборачивает источник так, что перед коммитом в Kafka позиции
+// топика topic сохраняются в БД. При рестарте чтение продолжается с них.
+func WithLedger(src Source, ledger Ledger, groupID, topic string) Source {
+	return &ledgerSource{Source: src, ledger: ledger, groupID: groupID, topic: topic}
+}
+
+type ledgerSource struct {
+	Source
+	ledger  Ledger
+	groupID string
+	topic   string
+}
+
+func (s *ledgerSource) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	positions := make([]storage.Origin, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Topic == s.topic {
+			positions = append(positions, storage.Origin{
+				GroupID:   s.groupID,
+				Topic:     m.Topic,
+				Partition: m.Partition,
+				Offset:    m.Offset,
+			})
+		}
+	}
+	if err := s.ledger.StoreOffsets(ctx, positions); err != nil {
+		return fmt.Errorf("store offsets: %w", err)
+	}
+	return s.Source.CommitMessages(ctx, msgs...)
+}
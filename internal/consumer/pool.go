@@ -0,0 +1,209 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/kafka"
+)
+
+// Source — источник сообщений с ручным коммитом (kafka.Consumer).
+type Source interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+const (
+	DispatchPartition = "partition"
+	DispatchKey       = "key"
+)
+
+type PoolConfig struct {
+	Workers   int
+	Dispatch  string // partition|key
+	QueueSize int    // буфер на воркер
+}
+
+// Pool раздаёт сообщения воркерам по партиции или хэшу ключа, так что
+// сообщения одного ключа обрабатываются по порядку. Смещение партиции
+// коммитится, только когда завершены все более ранние сообщения этой партиции.
+type Pool struct {
+	h   *Handler
+	src Source
+	cfg PoolConfig
+	log *slog.Logger
+
+	queues  []chan *inflight
+	tracker *offsetTracker
+
+	commitMu  sync.Mutex
+	committed map[partitionKey]int64
+}
+
+func NewPool(h *Handler, src Source, cfg PoolConfig, log *slog.Logger) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.Dispatch != DispatchKey {
+		cfg.Dispatch = DispatchPartition
+	}
+	return &Pool{
+		h:         h,
+		src:       src,
+		cfg:       cfg,
+		log:       log,
+		tracker:   newOffsetTracker(),
+		committed: make(map[partitionKey]int64),
+	}
+}
+
+// Run читает сообщения до отмены ctx, после чего дожидается обработки
+// уже выбранных сообщений и коммитит их смещения.
+func (p *Pool) Run(ctx context.Context) {
+	// обработка в воркерах не прерывается по ctx — при остановке дорабатываем очередь
+	workCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	p.queues = make([]chan *inflight, p.cfg.Workers)
+	for i := range p.queues {
+		q := make(chan *inflight, p.cfg.QueueSize)
+		p.queues[i] = q
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range q {
+				ok := p.h.Handle(workCtx, e.msg)
+				if m, commit := p.tracker.finish(e, ok); commit {
+					p.commit(m)
+				}
+			}
+		}()
+	}
+
+	for {
+		msg, err := p.src.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				p.log.Info("shutdown: stop reading, draining workers")
+				break
+			}
+			p.log.Error("kafka read failed", slog.Any("err", err))
+			time.Sleep(time.Second)
+			continue
+		}
+		e := p.tracker.add(msg)
+		p.queues[p.worker(msg)] <- e
+	}
+
+	for _, q := range p.queues {
+		close(q)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) worker(msg kafka.Message) int {
+	if p.cfg.Dispatch == DispatchKey && len(msg.Key) > 0 {
+		h := fnv.New32a()
+		_, _ = h.Write(msg.Key)
+		return int(h.Sum32() % uint32(p.cfg.Workers))
+	}
+	return msg.Partition % p.cfg.Workers
+}
+
+// commit коммитит смещение, не допуская отката назад при гонке воркеров.
+func (p *Pool) commit(msg kafka.Message) {
+	p.commitMu.Lock()
+	defer p.commitMu.Unlock()
+
+	k := partitionKey{topic: msg.Topic, partition: msg.Partition}
+	if last, ok := p.committed[k]; ok && msg.Offset <= last {
+		return
+	}
+	if err := CommitWithTimeout(context.Background(), p.src, msg, MsgLogger(p.log, msg)); err == nil {
+		p.committed[k] = msg.Offset
+	}
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+type inflight struct {
+	msg  kafka.Message
+	done bool
+	ok   bool // Handle разрешил коммит
+}
+
+// offsetTracker хранит выбранные, но ещё не закоммиченные сообщения по партициям
+// в порядке чтения.
+type offsetTracker struct {
+	mu    sync.Mutex
+	parts map[partitionKey][]*inflight
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{parts: make(map[partitionKey][]*inflight)}
+}
+
+func (t *offsetTracker) add(msg kafka.Message) *inflight {
+	e := &inflight{msg: msg}
+	k := partitionKey{topic: msg.Topic, partition: msg.Partition}
+
+	t.mu.Lock()
+	t.parts[k] = append(t.parts[k], e)
+	t.mu.Unlock()
+	return e
+}
+
+// finish отмечает сообщение обработанным и возвращает сообщение, смещение которого
+// теперь можно закоммитить: последнее успешно обработанное из непрерывного префикса
+// завершённых. Неуспешные (ok=false) сообщения, как и в последовательном режиме,
+// не коммитятся сами, но и не блокируют партицию.
+func (t *offsetTracker) finish(e *inflight, ok bool) (kafka.Message, bool) {
+	k := partitionKey{topic: e.msg.Topic, partition: e.msg.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e.done, e.ok = true, ok
+
+	q := t.parts[k]
+	var last *inflight
+	for len(q) > 0 && q[0].done {
+		if q[0].ok {
+			last = q[0]
+		}
+		q[0] = nil
+		q = q[1:]
+	}
+	if len(q) == 0 {
+		delete(t.parts, k)
+	} else {
+		t.parts[k] = q
+	}
+
+	if last == nil {
+		return kafka.Message{}, false
+	}
+	return last.msg, true
+}
+
+// CommitWithTimeout коммитит смещение сообщения с таймаутом.
+func CommitWithTimeout(parent context.Context, src Source, msg kafka.Message, log *slog.Logger) error {
+	commitCtx, cancel := context.WithTimeout(parent, 5*time.Second)
+	defer cancel()
+	if err := src.CommitMessages(commitCtx, msg); err != nil {
+		log.Error("commit failed; message will be reprocessed", slog.Any("err", err))
+		return err
+	}
+	log.Debug("offset commited")
+	return nil
+}
@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/sillkiw/wb-l0/internal/kafka"
+)
+
+func mkMsg(partition int, offset int64) kafka.Message {
+	return kafka.Message{Topic: "orders", Partition: partition, Offset: offset}
+}
+
+func TestOffsetTracker_CommitsContiguousPrefix(t *testing.T) {
+	tr := newOffsetTracker()
+	e10 := tr.add(mkMsg(0, 10))
+	e11 := tr.add(mkMsg(0, 11))
+	e12 := tr.add(mkMsg(0, 12))
+
+	// 12 и 11 завершились раньше 10 — коммитить нельзя
+	if _, ok := tr.finish(e12, true); ok {
+		t.Fatalf("offset 12 must wait for 10 and 11")
+	}
+	if _, ok := tr.finish(e11, true); ok {
+		t.Fatalf("offset 11 must wait for 10")
+	}
+
+	m, ok := tr.finish(e10, true)
+	if !ok || m.Offset != 12 {
+		t.Fatalf("want commit of 12, got %v (ok=%v)", m.Offset, ok)
+	}
+	if len(tr.parts) != 0 {
+		t.Errorf("tracker must be empty, got %d partitions", len(tr.parts))
+	}
+}
+
+func TestOffsetTracker_FailedMessageIsNotCommitted(t *testing.T) {
+	tr := newOffsetTracker()
+	e1 := tr.add(mkMsg(0, 1))
+	e2 := tr.add(mkMsg(0, 2))
+
+	if _, ok := tr.finish(e1, true); !ok {
+		t.Fatalf("want commit of 1")
+	}
+	if _, ok := tr.finish(e2, false); ok {
+		t.Fatalf("failed message must not be committed")
+	}
+
+	// следующий успешный коммит проходит «поверх» неуспешного
+	e3 := tr.add(mkMsg(0, 3))
+	if m, ok := tr.finish(e3, true); !ok || m.Offset != 3 {
+		t.Fatalf("want commit of 3, got %v (ok=%v)", m.Offset, ok)
+	}
+}
+
+func TestOffsetTracker_PartitionsAreIndependent(t *testing.T) {
+	tr := newOffsetTracker()
+	a := tr.add(mkMsg(0, 5))
+	b := tr.add(mkMsg(1, 7))
+
+	if m, ok := tr.finish(b, true); !ok || m.Partition != 1 || m.Offset != 7 {
+		t.Fatalf("partition 1 must not wait for partition 0")
+	}
+	if m, ok := tr.finish(a, true); !ok || m.Offset != 5 {
+		t.Fatalf("want commit of 5 in partition 0")
+	}
+}
@@ -0,0 +1,39 @@
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunDelayed обрабатывает топик ретраев: каждое сообщение ждёт, пока с момента
+// его публикации пройдёт delay, и только потом уходит в Handler.
+// Сообщения в топике упорядочены по времени, поэтому ожидание головы партиции
+// не задерживает следующие сверх их собственной задержки.
+func RunDelayed(ctx context.Context, src Source, delay time.Duration, h *Handler, log *slog.Logger) {
+	for {
+		msg, err := src.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error("kafka read failed", slog.Any("err", err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if wait := time.Until(msg.Timestamp.Add(delay)); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return // не закоммичено - перечитаем после рестарта
+			case <-t.C:
+			}
+		}
+
+		if commit := h.Handle(ctx, msg); commit {
+			_ = CommitWithTimeout(context.Background(), src, msg, MsgLogger(log, msg))
+		}
+	}
+}
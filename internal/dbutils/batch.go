@@ -5,12 +5,54 @@ import (
 	"strings"
 )
 
+// MaxParams — предел числа параметров в одном запросе PostgreSQL.
+const MaxParams = 65535
+
 // BuildBatchInsert формирует SQL-запрос для batch insert
 func BuildBatchInsert(table string, columns []string, rows [][]interface{}) (string, []interface{}) {
 	if len(rows) == 0 {
 		return "", nil
 	}
 
+	query, args := buildValues(table, columns, rows)
+	query += " ON CONFLICT DO NOTHING"
+
+	return query, args
+}
+
+// BuildBatchUpsert формирует batch insert с ON CONFLICT (conflict) DO UPDATE
+// по колонкам update.
+func BuildBatchUpsert(table string, columns []string, rows [][]interface{}, conflict string, update []string) (string, []interface{}) {
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	query, args := buildValues(table, columns, rows)
+
+	sets := make([]string, 0, len(update))
+	for _, c := range update {
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+	}
+	query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", conflict, strings.Join(sets, ", "))
+
+	return query, args
+}
+
+// ChunkRows делит строки на пачки, каждая из которых укладывается в MaxParams.
+func ChunkRows(columns []string, rows [][]interface{}) [][][]interface{} {
+	per := MaxParams / max(len(columns), 1)
+	var out [][][]interface{}
+	for len(rows) > per {
+		out = append(out, rows[:per])
+		rows = rows[per:]
+	}
+	if len(rows) > 0 {
+		out = append(out, rows)
+	}
+	return out
+}
+
+func buildValues(table string, columns []string, rows [][]interface{}) (string, []interface{}) {
 	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES ",
 		table,
 		strings.Join(columns, ", "),
@@ -31,7 +73,5 @@ func BuildBatchInsert(table string, columns []string, rows [][]interface{}) (str
 	}
 
 	query += strings.Join(placeholders, ", ")
-	query += " ON CONFLICT DO NOTHING"
-
 	return query, args
 }
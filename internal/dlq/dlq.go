@@ -9,11 +9,12 @@ import (
 
 	skafka "github.com/segmentio/kafka-go"
 	ikafka "github.com/sillkiw/wb-l0/internal/kafka" // твой тип Message
+	"github.com/sillkiw/wb-l0/internal/validation"
 )
 
 // Publisher — опционально, если хочешь иметь общий интерфейс для Close().
 type Publisher interface {
-	Send(ctx context.Context, m ikafka.Message, reason string, attempt int) error
+	Send(ctx context.Context, m ikafka.Message, f Failure) error
 	Close() error
 }
 
@@ -41,34 +42,98 @@ func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error)
 
 func (p *KafkaPublisher) Close() error { return p.w.Close() }
 
+// Failure — почему сообщение отправляется в DLQ.
+type Failure struct {
+	Reason      string                  // код причины (unmarshal_failed и т.п.)
+	Detail      string                  // уточнение причины (например, имя ограничения)
+	Errors      []validation.FieldError // ошибки валидации (validation_failed)
+	DecodeError string                  // текст ошибки разбора (unmarshal_failed)
+	Attempt     int
+
+	GroupID  string // группа консюмеров, обработавшая сообщение
+	Instance string // хост/ID экземпляра консюмера
+
+	FirstFailedAt time.Time // первая неудачная попытка; нулевое — сейчас
+}
+
 // Envelope — что положим в Value DLQ-сообщения.
 type Envelope struct {
-	OriginalTopic     string    `json:"original_topic"`
-	OriginalPartition int       `json:"original_partition"`
-	OriginalOffset    int64     `json:"original_offset"`
-	Key               string    `json:"key"`     // исходный ключ сообщения (как строка)
-	Reason            string    `json:"reason"`  // код причины (unmarshal_failed и т.п.)
-	Attempt           int       `json:"attempt"` // счётчик попыток (если ведёшь)
-	Payload           []byte    `json:"payload"` // исходный payload (base64 в JSON — это нормально)
-	Timestamp         time.Time `json:"timestamp"`
+	OriginalTopic     string       `json:"original_topic"`
+	OriginalPartition int          `json:"original_partition"`
+	OriginalOffset    int64        `json:"original_offset"`
+	OriginalTimestamp time.Time    `json:"original_timestamp"`     // время исходного сообщения (версия заказа)
+	Key               string       `json:"key"`                    // исходный ключ сообщения (как строка)
+	Reason            string       `json:"reason"`                 // код причины (unmarshal_failed и т.п.)
+	Detail            string       `json:"detail,omitempty"`       // уточнение причины (например, имя ограничения)
+	Errors            []FieldError `json:"errors,omitempty"`       // ошибки валидации по полям
+	DecodeError       string       `json:"decode_error,omitempty"` // текст ошибки разбора JSON
+	Attempt           int          `json:"attempt"`                // счётчик попыток (если ведёшь)
+	GroupID           string       `json:"group_id,omitempty"`
+	Instance          string       `json:"instance,omitempty"`
+	Payload           []byte       `json:"payload"` // исходный payload (base64 в JSON — это нормально)
+	FirstFailedAt     time.Time    `json:"first_failed_at"`
+	Timestamp         time.Time    `json:"timestamp"`
 }
 
-func dlqKey(m ikafka.Message) []byte {
-	// Ключ для дедупликации (удобно, если на DLQ включите compact,delete)
-	return []byte(fmt.Sprintf("%s:%d:%d", m.Topic, m.Partition, m.Offset))
+// Version — версия заказа из сообщения: время исходного сообщения. В
+// конвертах, записанных до появления original_timestamp, — время первой
+// неудачи (ближайшее известное к исходному), иначе время конверта.
+func (e Envelope) Version() time.Time {
+	switch {
+	case !e.OriginalTimestamp.IsZero():
+		return e.OriginalTimestamp
+	case !e.FirstFailedAt.IsZero():
+		return e.FirstFailedAt
+	}
+	return e.Timestamp
+}
+
+// FieldError — ошибка валидации поля в конверте.
+type FieldError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
 }
 
-func (p *KafkaPublisher) Send(ctx context.Context, m ikafka.Message, reason string, attempt int) error {
-	env := Envelope{
-		OriginalTopic:     m.Topic,
-		OriginalPartition: m.Partition,
-		OriginalOffset:    m.Offset,
+// NewEnvelope собирает конверт для сообщения m.
+func NewEnvelope(m ikafka.Message, f Failure) Envelope {
+	topic, partition, offset := m.Origin()
+	now := time.Now().UTC()
+	first := f.FirstFailedAt.UTC()
+	if f.FirstFailedAt.IsZero() {
+		first = now
+	}
+	var errs []FieldError
+	for _, fe := range f.Errors {
+		errs = append(errs, FieldError{Path: fe.Path, Code: string(fe.Code), Message: fe.Message})
+	}
+	return Envelope{
+		OriginalTopic:     topic,
+		OriginalPartition: partition,
+		OriginalOffset:    offset,
+		OriginalTimestamp: m.OriginTimestamp().UTC(),
 		Key:               string(m.Key),
-		Reason:            reason,
-		Attempt:           attempt,
+		Reason:            f.Reason,
+		Detail:            f.Detail,
+		Errors:            errs,
+		DecodeError:       f.DecodeError,
+		Attempt:           f.Attempt,
+		GroupID:           f.GroupID,
+		Instance:          f.Instance,
 		Payload:           m.Value,
-		Timestamp:         time.Now().UTC(),
+		FirstFailedAt:     first,
+		Timestamp:         now,
 	}
+}
+
+func dlqKey(m ikafka.Message) []byte {
+	// Ключ для дедупликации (удобно, если на DLQ включите compact,delete)
+	topic, partition, offset := m.Origin()
+	return []byte(fmt.Sprintf("%s:%d:%d", topic, partition, offset))
+}
+
+func (p *KafkaPublisher) Send(ctx context.Context, m ikafka.Message, f Failure) error {
+	env := NewEnvelope(m, f)
 	val, err := json.Marshal(env)
 	if err != nil {
 		return err
@@ -85,8 +150,8 @@ func (p *KafkaPublisher) Send(ctx context.Context, m ikafka.Message, reason stri
 		Key:   dlqKey(m),
 		Value: val,
 		Headers: []skafka.Header{
-			{Key: "x-dlq-reason", Value: []byte(reason)},
-			{Key: "x-original-topic", Value: []byte(m.Topic)},
+			{Key: "x-dlq-reason", Value: []byte(env.Reason)},
+			{Key: "x-original-topic", Value: []byte(env.OriginalTopic)},
 		},
 	})
 }
@@ -94,5 +159,5 @@ func (p *KafkaPublisher) Send(ctx context.Context, m ikafka.Message, reason stri
 // Заглушка для dev/тестов
 type NoopPublisher struct{}
 
-func (NoopPublisher) Send(context.Context, ikafka.Message, string, int) error { return nil }
-func (NoopPublisher) Close() error                                            { return nil }
+func (NoopPublisher) Send(context.Context, ikafka.Message, Failure) error { return nil }
+func (NoopPublisher) Close() error                                        { return nil }
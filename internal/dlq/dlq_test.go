@@ -0,0 +1,60 @@
+package dlq
+
+import (
+	"testing"
+	"time"
+
+	ikafka "github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/validation"
+)
+
+func TestNewEnvelope(t *testing.T) {
+	m := ikafka.Message{
+		Key:       []byte("ord_1"),
+		Value:     []byte(`{}`),
+		Topic:     "orders-retry-5s",
+		Partition: 0,
+		Offset:    7,
+		Headers: []ikafka.Header{
+			{Key: ikafka.HeaderOriginalTopic, Value: []byte("orders")},
+			{Key: ikafka.HeaderOriginalPartition, Value: []byte("2")},
+			{Key: ikafka.HeaderOriginalOffset, Value: []byte("42")},
+			{Key: ikafka.HeaderOriginalTimestamp, Value: []byte("1735700000000")},
+		},
+	}
+	first := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	env := NewEnvelope(m, Failure{
+		Reason:        "validation_failed",
+		Errors:        []validation.FieldError{{Path: "items[0].price", Code: validation.CodeOutOfRange, Message: "must be > 0"}},
+		Attempt:       2,
+		GroupID:       "orders-consumer",
+		Instance:      "host-1",
+		FirstFailedAt: first,
+	})
+
+	if env.OriginalTopic != "orders" || env.OriginalPartition != 2 || env.OriginalOffset != 42 {
+		t.Fatalf("origin = %s:%d:%d, want orders:2:42", env.OriginalTopic, env.OriginalPartition, env.OriginalOffset)
+	}
+	if len(env.Errors) != 1 || env.Errors[0] != (FieldError{Path: "items[0].price", Code: "out_of_range", Message: "must be > 0"}) {
+		t.Fatalf("errors = %+v", env.Errors)
+	}
+	if want := time.UnixMilli(1735700000000); !env.OriginalTimestamp.Equal(want) || !env.Version().Equal(want) {
+		t.Fatalf("original_timestamp = %v, version = %v, want %v", env.OriginalTimestamp, env.Version(), want)
+	}
+	if !env.FirstFailedAt.Equal(first) {
+		t.Fatalf("first_failed_at = %v, want %v", env.FirstFailedAt, first)
+	}
+	if env.GroupID != "orders-consumer" || env.Instance != "host-1" || env.Attempt != 2 {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+
+	// первая неудача — время первой ошибки совпадает с временем конверта
+	env = NewEnvelope(m, Failure{Reason: "unmarshal_failed", DecodeError: "unexpected EOF"})
+	if !env.FirstFailedAt.Equal(env.Timestamp) {
+		t.Fatalf("first_failed_at = %v, want %v", env.FirstFailedAt, env.Timestamp)
+	}
+	if env.DecodeError != "unexpected EOF" {
+		t.Fatalf("decode_error = %q", env.DecodeError)
+	}
+}
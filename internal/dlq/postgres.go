@@ -0,0 +1,265 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "github.com/lib/pq"
+
+	ikafka "github.com/sillkiw/wb-l0/internal/kafka"
+)
+
+// Статусы записи dead_letters.
+const (
+	StatusNew      = "new"
+	StatusResolved = "resolved" // причина устранена (например, сообщение переиграно)
+	StatusIgnored  = "ignored"  // разобрано, действий не требуется
+)
+
+// ErrNotFound — записи с таким id нет.
+var ErrNotFound = errors.New("dead letter not found")
+
+// DeadLetter — запись таблицы dead_letters.
+type DeadLetter struct {
+	ID int64 `json:"id"`
+	Envelope
+	PayloadJSON     json.RawMessage `json:"payload_json,omitempty"` // payload, если это валидный JSON
+	Status          string          `json:"status"`
+	StatusChangedAt *time.Time      `json:"status_changed_at,omitempty"`
+}
+
+// ListFilter — условия выборки. Нулевые поля не фильтруют.
+type ListFilter struct {
+	Reason string
+	Key    string
+	Status string
+	Since  time.Time // created_at >= Since
+	Until  time.Time // created_at < Until
+	After  int64     // курсор: только записи с id < After
+	Limit  int
+}
+
+// PostgresStore пишет DLQ в таблицу dead_letters и читает её для API.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	if dsn == "" {
+		return nil, errors.New("dlq: empty dsn")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open db failed: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping db failed: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+func (p *PostgresStore) Close() error { return p.db.Close() }
+
+// Send сохраняет конверт. Повторная отправка того же исходного сообщения
+// (по координатам) не создаёт дубликат.
+func (p *PostgresStore) Send(ctx context.Context, m ikafka.Message, f Failure) error {
+	env := NewEnvelope(m, f)
+
+	var errs []byte
+	if len(env.Errors) > 0 {
+		b, err := json.Marshal(env.Errors)
+		if err != nil {
+			return err
+		}
+		errs = b
+	}
+	var payloadJSON []byte
+	// jsonb не принимает \u0000 и невалидный UTF-8 — такой payload остаётся только в bytea
+	if json.Valid(env.Payload) && utf8.Valid(env.Payload) && !bytes.Contains(env.Payload, []byte(`\u0000`)) {
+		payloadJSON = env.Payload
+	}
+
+	// Если ctx без дедлайна — дадим защитный таймаут, чтобы не зависнуть при shutdown.
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 7*time.Second)
+		defer cancel()
+	}
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO dead_letters (
+			original_topic, original_partition, original_offset, message_key,
+			reason, detail, errors, decode_error, attempt, group_id, instance,
+			payload, payload_json, first_failed_at, created_at, original_timestamp
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (original_topic, original_partition, original_offset) DO NOTHING
+	`,
+		env.OriginalTopic, env.OriginalPartition, env.OriginalOffset, env.Key,
+		env.Reason, env.Detail, nullJSON(errs), env.DecodeError, env.Attempt, env.GroupID, env.Instance,
+		env.Payload, nullJSON(payloadJSON), env.FirstFailedAt, env.Timestamp, nullTime(env.OriginalTimestamp),
+	)
+	if err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+	return nil
+}
+
+const deadLetterCols = `
+	id, original_topic, original_partition, original_offset, message_key,
+	reason, detail, errors, decode_error, attempt, group_id, instance,
+	payload, payload_json, first_failed_at, created_at, status, status_changed_at, original_timestamp`
+
+// List возвращает записи от новых к старым.
+func (p *PostgresStore) List(ctx context.Context, f ListFilter) ([]DeadLetter, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Reason != "" {
+		add("reason = $%d", f.Reason)
+	}
+	if f.Key != "" {
+		add("message_key = $%d", f.Key)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		add("created_at < $%d", f.Until.UTC())
+	}
+	if f.After > 0 {
+		add("id < $%d", f.After)
+	}
+
+	query := `SELECT ` + deadLetterCols + ` FROM dead_letters`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DeadLetter
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows dead letters: %w", err)
+	}
+	return out, nil
+}
+
+// Get возвращает запись по id или ErrNotFound.
+func (p *PostgresStore) Get(ctx context.Context, id int64) (DeadLetter, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT `+deadLetterCols+` FROM dead_letters WHERE id = $1`, id)
+	d, err := scanDeadLetter(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DeadLetter{}, ErrNotFound
+	}
+	return d, err
+}
+
+// SetStatus помечает запись разобранной (resolved/ignored) или возвращает в new.
+func (p *PostgresStore) SetStatus(ctx context.Context, id int64, status string) (DeadLetter, error) {
+	switch status {
+	case StatusNew, StatusResolved, StatusIgnored:
+	default:
+		return DeadLetter{}, fmt.Errorf("dlq: unknown status %q", status)
+	}
+	row := p.db.QueryRowContext(ctx, `
+		UPDATE dead_letters
+		SET status = $2, status_changed_at = now()
+		WHERE id = $1
+		RETURNING `+deadLetterCols, id, status)
+	d, err := scanDeadLetter(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DeadLetter{}, ErrNotFound
+	}
+	return d, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeadLetter(r rowScanner) (DeadLetter, error) {
+	var (
+		d           DeadLetter
+		errs        []byte
+		payloadJSON []byte
+		changed     sql.NullTime
+		originalTS  sql.NullTime
+	)
+	err := r.Scan(
+		&d.ID, &d.OriginalTopic, &d.OriginalPartition, &d.OriginalOffset, &d.Key,
+		&d.Reason, &d.Detail, &errs, &d.DecodeError, &d.Attempt, &d.GroupID, &d.Instance,
+		&d.Payload, &payloadJSON, &d.FirstFailedAt, &d.Timestamp, &d.Status, &changed, &originalTS,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeadLetter{}, err
+		}
+		return DeadLetter{}, fmt.Errorf("scan dead letter: %w", err)
+	}
+	if len(errs) > 0 {
+		if err := json.Unmarshal(errs, &d.Errors); err != nil {
+			return DeadLetter{}, fmt.Errorf("decode dead letter errors: %w", err)
+		}
+	}
+	if len(payloadJSON) > 0 {
+		d.PayloadJSON = payloadJSON
+	}
+	if changed.Valid {
+		t := changed.Time.UTC()
+		d.StatusChangedAt = &t
+	}
+	if originalTS.Valid {
+		d.OriginalTimestamp = originalTS.Time.UTC()
+	}
+	d.FirstFailedAt = d.FirstFailedAt.UTC()
+	d.Timestamp = d.Timestamp.UTC()
+	return d, nil
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// nullJSON передаёт jsonb строкой: []byte драйвер отправил бы как bytea.
+func nullJSON(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
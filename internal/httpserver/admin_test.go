@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sillkiw/wb-l0/internal/cache"
+	"github.com/sillkiw/wb-l0/internal/dlq"
+)
+
+type fakeDeadLetters struct{ DeadLetterStore }
+
+func (fakeDeadLetters) List(context.Context, dlq.ListFilter) ([]dlq.DeadLetter, error) {
+	return nil, nil
+}
+
+func TestDeadLettersRequireAdminToken(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	get := func(s *Server, auth string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/dead-letters", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	s := New(log, &fakeStore{}, nil, Options{DeadLetters: fakeDeadLetters{}, AdminToken: "secret"})
+	if code := get(s, ""); code != http.StatusUnauthorized {
+		t.Fatalf("no token: %d, want 401", code)
+	}
+	if code := get(s, "Bearer wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: %d, want 401", code)
+	}
+	if code := get(s, "Bearer secret"); code != http.StatusOK {
+		t.Fatalf("valid token: %d, want 200", code)
+	}
+
+	// без токена админки API не регистрируется вовсе
+	s = New(log, &fakeStore{}, nil, Options{DeadLetters: fakeDeadLetters{}})
+	if code := get(s, "Bearer "); code == http.StatusOK {
+		t.Fatalf("without ADMIN_TOKEN: %d, want non-200", code)
+	}
+}
+
+func TestDebugVarsRequireAdminToken(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	get := func(s *Server, auth string) int {
+		r := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	s := New(log, &fakeStore{}, nil, Options{AdminToken: "secret"})
+	if code := get(s, ""); code != http.StatusUnauthorized {
+		t.Fatalf("no token: %d, want 401", code)
+	}
+	if code := get(s, "Bearer secret"); code != http.StatusOK {
+		t.Fatalf("valid token: %d, want 200", code)
+	}
+
+	s = New(log, &fakeStore{}, nil, Options{})
+	if code := get(s, ""); code != http.StatusNotFound {
+		t.Fatalf("without ADMIN_TOKEN: %d, want 404", code)
+	}
+}
+
+func TestLoaderStatsArePublic(t *testing.T) {
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &fakeStore{}, nil, Options{})
+	getOrder(t, s.mux, "ord_1")
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/loader", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/debug/loader: %d, want 200 without token", rec.Code)
+	}
+	var st cache.LoaderStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil || st.Loads != 1 {
+		t.Fatalf("/debug/loader = %s (%v), want loads=1", rec.Body, err)
+	}
+}
@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// versionsStore отдаёт OrderAsOf из versions (по возрастанию ingested_at).
+type versionsStore struct {
+	OrderStore
+	versions []storage.OrderVersion
+}
+
+func (f *versionsStore) OrderAsOf(_ context.Context, _ string, t time.Time) (storage.OrderVersion, error) {
+	for i := len(f.versions) - 1; i >= 0; i-- {
+		if !f.versions[i].IngestedAt.After(t) {
+			return f.versions[i], nil
+		}
+	}
+	return storage.OrderVersion{}, storage.ErrNotFound
+}
+
+func TestGetOrderAsOf(t *testing.T) {
+	t0 := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	store := &versionsStore{versions: []storage.OrderVersion{
+		{Version: 1, IngestedAt: t0, Order: domain.Order{OrderUID: "ord_1", TrackNumber: "WB1"}},
+		{Version: 2, IngestedAt: t0.Add(time.Hour), Order: domain.Order{OrderUID: "ord_1", TrackNumber: "WB2"}},
+	}}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{})
+	get := func(asOf string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/ord_1?as_of="+asOf, nil))
+		return rec
+	}
+
+	if rec := get("yesterday"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad as_of: %d, want 400", rec.Code)
+	}
+	if rec := get("2025-01-15T11:00:00Z"); rec.Code != http.StatusNotFound {
+		t.Fatalf("as_of before first version: %d, want 404", rec.Code)
+	}
+
+	rec := get("2025-01-15T12:30:00Z")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("as_of between versions: %d, want 200", rec.Code)
+	}
+	if v := rec.Header().Get("X-Order-Version"); v != "1" {
+		t.Fatalf("X-Order-Version = %q, want 1", v)
+	}
+	if src := rec.Header().Get("X-Source"); src != "history" {
+		t.Fatalf("X-Source = %q, want history", src)
+	}
+	if v := get("2025-01-15T14:00:00Z").Header().Get("X-Order-Version"); v != "2" {
+		t.Fatalf("X-Order-Version after second version = %q, want 2", v)
+	}
+}
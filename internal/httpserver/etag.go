@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// cachedOrder — запись кэша: заказ и уже готовый ответ для него.
+// Body и ETag посчитаны без блока _meta, т.е. для обычного GET /order/{id}.
+type cachedOrder struct {
+	Order   storage.OrderWithMeta
+	Body    []byte
+	ETag    string
+	Version time.Time // время исходного сообщения; нулевое — неизвестно
+}
+
+// orderCost — примерный объём записи в памяти: готовое тело ответа плюс
+// разобранный заказ (по опыту — того же порядка, что его JSON) и накладные
+// расходы на ключ, элемент списка и заголовки строк.
+func orderCost(e cachedOrder) int64 {
+	return int64(2*len(e.Body)) + 512
+}
+
+func newCachedOrder(o storage.OrderWithMeta) (cachedOrder, error) {
+	body, etag, err := encodeOrder(o.Order)
+	if err != nil {
+		return cachedOrder{}, err
+	}
+	e := cachedOrder{Order: o, Body: body, ETag: etag}
+	if o.Meta != nil && o.Meta.Version != nil {
+		e.Version = *o.Meta.Version
+	}
+	return e, nil
+}
+
+// encodeOrder кодирует заказ так же, как respondJSON, и считает по этим
+// байтам сильный ETag: одинаковый JSON — одинаковый тег.
+func encodeOrder(v any) ([]byte, string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	return body, `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// lastModified — время последней принятой версии заказа, если оно известно.
+func lastModified(o storage.OrderWithMeta) time.Time {
+	if o.Meta == nil || o.Meta.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return *o.Meta.UpdatedAt
+}
+
+// writeOrder отдаёт готовое тело заказа с валидаторами и Cache-Control,
+// а на совпавший условный запрос — 304 без тела.
+func (s *Server) writeOrder(w http.ResponseWriter, r *http.Request, body []byte, etag string, mod time.Time) {
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !mod.IsZero() {
+		h.Set("Last-Modified", mod.UTC().Format(http.TimeFormat))
+	}
+	if s.cacheControl != "" {
+		h.Set("Cache-Control", s.cacheControl)
+	}
+	if notModified(r, etag, mod) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// notModified проверяет If-None-Match, а если его нет — If-Modified-Since (RFC 9110, 13.2.2).
+func notModified(r *http.Request, etag string, mod time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			// для If-None-Match сравнение слабое: W/ не учитывается
+			if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if mod.IsZero() {
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified отдаётся с точностью до секунды
+	return !mod.Truncate(time.Second).After(ims)
+}
@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	mod := time.Date(2025, 1, 15, 12, 0, 0, 500_000_000, time.UTC)
+	const etag = `"abc"`
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   bool
+	}{
+		{"no validators", nil, false},
+		{"etag match", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag in list", map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"etag star", map[string]string{"If-None-Match": `*`}, true},
+		{"etag mismatch", map[string]string{"If-None-Match": `"x"`}, false},
+		{"etag wins over date", map[string]string{
+			"If-None-Match":     `"x"`,
+			"If-Modified-Since": mod.Add(time.Hour).Format(http.TimeFormat),
+		}, false},
+		{"same second", map[string]string{"If-Modified-Since": mod.Format(http.TimeFormat)}, true},
+		{"older copy", map[string]string{"If-Modified-Since": mod.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"bad date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if got := notModified(r, etag, mod); got != tt.want {
+				t.Fatalf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
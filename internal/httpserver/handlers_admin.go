@@ -0,0 +1,120 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/cache"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// adminWarmupTimeout ограничивает перепрогрев, запущенный через API.
+const adminWarmupTimeout = 2 * time.Minute
+
+type cacheStats struct {
+	Orders  cache.Stats       `json:"orders"`
+	Missing *cache.Stats      `json:"missing,omitempty"` // негативный кэш, если включён
+	Loader  cache.LoaderStats `json:"loader"`
+}
+
+type cacheKeyView struct {
+	OrderUID  string                `json:"order_uid"`
+	ETag      string                `json:"etag"`
+	ExpiresAt time.Time             `json:"expires_at"`
+	Order     storage.OrderWithMeta `json:"order"`
+}
+
+// withAdmin пропускает только запросы с Authorization: Bearer <ADMIN_TOKEN>.
+func (s *Server) withAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// CacheStats возвращает счётчики кэша заказов, негативного кэша и загрузчика.
+func (s *Server) CacheStats() any {
+	st := cacheStats{Orders: s.cache.Stats(), Loader: s.loader.Stats()}
+	if s.missing != nil {
+		m := s.missing.Stats()
+		st.Missing = &m
+	}
+	return st
+}
+
+// handleCacheStats — GET /admin/cache/stats
+func (s *Server) handleCacheStats(w http.ResponseWriter, _ *http.Request) {
+	s.respondJSON(w, http.StatusOK, s.CacheStats())
+}
+
+// handleCacheGetKey — GET /admin/cache/orders/{id}: что лежит в кэше, без влияния на LRU.
+func (s *Server) handleCacheGetKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	e, exp, ok := s.cache.Peek(id)
+	if !ok {
+		http.Error(w, "not cached", http.StatusNotFound)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, cacheKeyView{OrderUID: id, ETag: e.ETag, ExpiresAt: exp, Order: e.Order})
+}
+
+// handleCacheDeleteKey — DELETE /admin/cache/orders/{id}
+func (s *Server) handleCacheDeleteKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.InvalidateOrder(id)
+	s.log.Info("admin: cache key deleted", slog.String("order_uid", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCachePurge — POST /admin/cache/purge
+func (s *Server) handleCachePurge(w http.ResponseWriter, _ *http.Request) {
+	s.PurgeCache()
+	s.log.Info("admin: cache purged")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCacheWarmup — POST /admin/cache/warmup?limit=
+// Прогрев идёт в фоне; одновременно допускается только один.
+func (s *Server) handleCacheWarmup(w http.ResponseWriter, r *http.Request) {
+	limit := s.cacheSize
+	if limit <= 0 {
+		limit = 1000 // кэш ограничен только по байтам
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if !s.warming.CompareAndSwap(false, true) {
+		http.Error(w, "warmup already running", http.StatusConflict)
+		return
+	}
+
+	go func() {
+		defer s.warming.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), adminWarmupTimeout)
+		defer cancel()
+
+		start := time.Now()
+		n, err := s.warmCache(ctx, limit, 500)
+		if err != nil {
+			s.log.Warn("admin: cache warmup failed", slog.Int("loaded", n), slog.Any("err", err))
+			return
+		}
+		s.log.Info("admin: cache warmup done", slog.Int("loaded", n), slog.Duration("dur", time.Since(start)))
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
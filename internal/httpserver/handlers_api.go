@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,37 +26,282 @@ func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// cache
-	if o, ok := s.cache.Get(id); ok {
+	// состояние на момент as_of — из истории версий, мимо кэша
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		asOf, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "bad as_of, want RFC3339", http.StatusBadRequest)
+			return
+		}
+		s.getOrderAsOf(w, r, id, asOf)
+		return
+	}
+
+	// cache: свежая запись — сразу; просроченная не дольше stale-while-revalidate —
+	// тоже сразу, а в фоне перечитывается
+	stale, staleFor, cached := s.cache.GetStale(id)
+	if cached && staleFor == 0 {
 		w.Header().Set("X-Source", "cache")
-		w.Header().Set("Content-Type", "application/json")
-		s.respondJSON(w, http.StatusOK, o)
+		s.serveOrder(w, r, stale)
+		return
+	}
+	if cached && staleFor <= s.staleWhileRevalidate {
+		s.refreshOrder(id)
+		s.serveStale(w, r, stale, `110 - "Response is Stale"`)
+		return
+	}
+	if s.knownMissing(id) {
+		w.Header().Set("X-Source", "cache-miss")
+		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
 
-	// db
+	// db: одновременные промахи по одному id делят одну загрузку
+	e, _, err := s.loader.Load(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			w.Header().Set("X-Source", "miss")
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		// БД недоступна — лучше недавняя копия, чем 500
+		if cached && staleFor <= s.staleIfError {
+			s.log.Warn("get order failed, serving stale copy",
+				slog.String("order_uid", id),
+				slog.Duration("stale_for", staleFor),
+				slog.Any("err", err),
+			)
+			s.serveStale(w, r, stale, `111 - "Revalidation Failed"`)
+			return
+		}
+		w.Header().Set("X-Source", "miss")
+		s.log.Error("get order failed",
+			slog.String("order_uid", id),
+			slog.Any("err", err),
+		)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Source", "db")
+	s.serveOrder(w, r, e)
+}
+
+// serveStale отдаёт просроченную запись кэша с пометкой.
+func (s *Server) serveStale(w http.ResponseWriter, r *http.Request, e cachedOrder, warning string) {
+	w.Header().Set("X-Source", "stale")
+	w.Header().Set("Warning", warning)
+	s.serveOrder(w, r, e)
+}
+
+// loadOrder — LoadFunc для s.loader: читает заказ из БД. В кэш результат
+// кладёт orderLoaded.
+func (s *Server) loadOrder(ctx context.Context, id string) (cachedOrder, error) {
+	o, err := s.store.GetOrder(ctx, id)
+	if err != nil {
+		return cachedOrder{}, err
+	}
+	e, err := newCachedOrder(o)
+	if err != nil {
+		return cachedOrder{}, fmt.Errorf("encode order: %w", err)
+	}
+	return e, nil
+}
+
+// orderLoaded получает результаты s.loader: найденный заказ кладётся в кэш,
+// отсутствующий — в негативный кэш. Загрузки, сброшенные InvalidateOrder,
+// сюда не попадают: они могли прочитать строку до изменения.
+func (s *Server) orderLoaded(id string, e cachedOrder, err error) {
+	switch {
+	case err == nil:
+		s.storeOrder(e)
+	case errors.Is(err, storage.ErrNotFound):
+		s.rememberMissing(id)
+	}
+}
+
+// serveOrder отдаёт заказ из записи кэша; с ?include=meta тело и ETag
+// считаются заново, т.к. в записи лежит ответ без _meta.
+func (s *Server) serveOrder(w http.ResponseWriter, r *http.Request, e cachedOrder) {
+	mod := lastModified(e.Order)
+	if !includeMeta(r) {
+		s.writeOrder(w, r, e.Body, e.ETag, mod)
+		return
+	}
+	body, etag, err := encodeOrder(e.Order)
+	if err != nil {
+		s.log.Error("encode order failed", slog.String("order_uid", e.Order.OrderUID), slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	s.writeOrder(w, r, body, etag, mod)
+}
+
+// getOrderAsOf отдаёт заказ в том виде, в каком он был записан на момент asOf.
+func (s *Server) getOrderAsOf(w http.ResponseWriter, r *http.Request, id string, asOf time.Time) {
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 
-	o, err := s.store.GetOrder(ctx, id)
+	v, err := s.store.OrderAsOf(ctx, id, asOf)
+	w.Header().Set("X-Source", "history")
 	if err != nil {
-		w.Header().Set("X-Source", "miss")
 		if errors.Is(err, storage.ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		s.log.Error("get order failed",
+		s.log.Error("get order as of failed",
 			slog.String("order_uid", id),
+			slog.Time("as_of", asOf),
+			slog.Any("err", err),
+		)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Order-Version", strconv.Itoa(v.Version))
+	// версия не меняется, так что и для неё работают условные запросы
+	body, etag, err := encodeOrder(v.Order)
+	if err != nil {
+		s.log.Error("encode order failed", slog.String("order_uid", id), slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	s.writeOrder(w, r, body, etag, v.IngestedAt)
+}
+
+const (
+	ordersDefaultLimit = 50
+	ordersMaxLimit     = 200
+)
+
+type ordersPage struct {
+	Items      []storage.OrderWithMeta `json:"items"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// handleListOrders — GET /api/v1/orders?customer_id=&track_number=&delivery_service=&from=&to=&limit=&cursor=
+func (s *Server) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := storage.OrderFilter{
+		CustomerID:      q.Get("customer_id"),
+		TrackNumber:     q.Get("track_number"),
+		DeliveryService: q.Get("delivery_service"),
+	}
+
+	var err error
+	if f.From, err = parseTimeParam(q.Get("from")); err != nil {
+		http.Error(w, "bad from, want RFC3339", http.StatusBadRequest)
+		return
+	}
+	if f.To, err = parseTimeParam(q.Get("to")); err != nil {
+		http.Error(w, "bad to, want RFC3339", http.StatusBadRequest)
+		return
+	}
+	limit := ordersDefaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, ordersMaxLimit)
+	}
+	after, err := storage.ParseOrderCursor(q.Get("cursor"))
+	if err != nil {
+		http.Error(w, "bad cursor", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	orders, err := s.store.ListOrders(ctx, f, after, limit)
+	if err != nil {
+		s.log.Error("list orders failed", slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range orders {
+		orders[i] = present(orders[i], r)
+	}
+	page := ordersPage{Items: orders}
+	if page.Items == nil {
+		page.Items = []storage.OrderWithMeta{}
+	}
+	if len(orders) == limit {
+		last := orders[len(orders)-1]
+		page.NextCursor = storage.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}.Encode()
+	}
+	s.respondJSON(w, http.StatusOK, page)
+}
+
+const lookupLimit = 100
+
+// handleLookup — GET /api/v1/lookup?track_number=|transaction=|request_id=|rid=
+// Ровно один параметр; найденные заказы заодно кладутся в кэш.
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var (
+		field storage.LookupField
+		value string
+	)
+	for _, f := range storage.LookupFields {
+		v := strings.TrimSpace(q.Get(string(f)))
+		if v == "" {
+			continue
+		}
+		if field != "" {
+			http.Error(w, "exactly one of track_number, transaction, request_id, rid expected", http.StatusBadRequest)
+			return
+		}
+		field, value = f, v
+	}
+	if field == "" {
+		http.Error(w, "exactly one of track_number, transaction, request_id, rid expected", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	orders, err := s.store.LookupOrders(ctx, field, value, lookupLimit)
+	if err != nil {
+		s.log.Error("lookup failed",
+			slog.String("field", string(field)),
+			slog.String("value", value),
 			slog.Any("err", err),
 		)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	if len(orders) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.cacheOrders(orders)
+	for i := range orders {
+		orders[i] = present(orders[i], r)
+	}
 
 	w.Header().Set("X-Source", "db")
-	w.Header().Set("Content-Type", "application/json")
-	s.cache.Set(id, o)
-	s.respondJSON(w, http.StatusOK, o)
+	s.respondJSON(w, http.StatusOK, ordersPage{Items: orders})
+}
+
+// present готовит заказ к ответу: блок _meta отдаётся только по ?include=meta.
+func present(o storage.OrderWithMeta, r *http.Request) storage.OrderWithMeta {
+	if !includeMeta(r) {
+		o.Meta = nil
+	}
+	return o
+}
+
+func includeMeta(r *http.Request) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(v) == "meta" {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Server) respondJSON(w http.ResponseWriter, code int, v any) {
@@ -0,0 +1,145 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/dlq"
+)
+
+const (
+	deadLettersDefaultLimit = 50
+	deadLettersMaxLimit     = 500
+)
+
+type deadLettersPage struct {
+	Items      []dlq.DeadLetter `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// handleListDeadLetters — GET /api/v1/dead-letters?reason=&key=&status=&since=&until=&limit=&cursor=
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := dlq.ListFilter{
+		Reason: q.Get("reason"),
+		Key:    q.Get("key"),
+		Status: q.Get("status"),
+		Limit:  deadLettersDefaultLimit,
+	}
+
+	var err error
+	if f.Since, err = parseTimeParam(q.Get("since")); err != nil {
+		http.Error(w, "bad since, want RFC3339", http.StatusBadRequest)
+		return
+	}
+	if f.Until, err = parseTimeParam(q.Get("until")); err != nil {
+		http.Error(w, "bad until, want RFC3339", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		f.Limit = min(n, deadLettersMaxLimit)
+	}
+	if v := q.Get("cursor"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "bad cursor", http.StatusBadRequest)
+			return
+		}
+		f.After = id
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	items, err := s.dead.List(ctx, f)
+	if err != nil {
+		s.log.Error("list dead letters failed", slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	page := deadLettersPage{Items: items}
+	if page.Items == nil {
+		page.Items = []dlq.DeadLetter{}
+	}
+	if len(items) == f.Limit {
+		page.NextCursor = strconv.FormatInt(items[len(items)-1].ID, 10)
+	}
+	s.respondJSON(w, http.StatusOK, page)
+}
+
+// handleGetDeadLetter — GET /api/v1/dead-letters/{id}
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	d, err := s.dead.Get(ctx, id)
+	if err != nil {
+		s.deadLetterError(w, id, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, d)
+}
+
+// handleSetDeadLetterStatus — POST /api/v1/dead-letters/{id}/resolve|ignore
+func (s *Server) handleSetDeadLetterStatus(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := deadLetterID(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		d, err := s.dead.SetStatus(ctx, id, status)
+		if err != nil {
+			s.deadLetterError(w, id, err)
+			return
+		}
+		s.log.Info("dead letter status changed",
+			slog.Int64("id", id),
+			slog.String("status", status),
+		)
+		s.respondJSON(w, http.StatusOK, d)
+	}
+}
+
+func (s *Server) deadLetterError(w http.ResponseWriter, id int64, err error) {
+	if errors.Is(err, dlq.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.log.Error("dead letter request failed", slog.Int64("id", id), slog.Any("err", err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func deadLetterID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad dead letter id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/jsondiff"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+const (
+	historyDefaultLimit = 50
+	historyMaxLimit     = 500
+)
+
+type historyResponse struct {
+	OrderUID string                 `json:"order_uid"`
+	Versions []storage.OrderVersion `json:"versions"`
+}
+
+type diffResponse struct {
+	OrderUID string            `json:"order_uid"`
+	From     int               `json:"from"` // 0 — пустой документ
+	To       int               `json:"to"`
+	Changes  []jsondiff.Change `json:"changes"`
+}
+
+// handleOrderHistory — GET /api/v1/orders/{uid}/history?limit=
+func (s *Server) handleOrderHistory(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	limit := historyDefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, historyMaxLimit)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	versions, err := s.store.OrderHistory(ctx, uid, limit)
+	if err != nil {
+		s.log.Error("order history failed", slog.String("order_uid", uid), slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if len(versions) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, historyResponse{OrderUID: uid, Versions: versions})
+}
+
+// handleOrderDiff — GET /api/v1/orders/{uid}/diff?from=&to=
+// По умолчанию to — последняя версия, from — предыдущая перед to.
+func (s *Server) handleOrderDiff(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	q := r.URL.Query()
+	from, okFrom := versionParam(q.Get("from"))
+	to, okTo := versionParam(q.Get("to"))
+	if !okFrom || !okTo {
+		http.Error(w, "bad version, want positive integer", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	toV, err := s.store.OrderVersion(ctx, uid, to)
+	if err != nil {
+		s.versionError(w, uid, err)
+		return
+	}
+	if q.Get("from") == "" {
+		from = toV.Version - 1
+	}
+
+	var fromDoc any // нет исходной версии — сравниваем с пустым документом
+	if from > 0 {
+		fromV, err := s.store.OrderVersion(ctx, uid, from)
+		if err != nil {
+			s.versionError(w, uid, err)
+			return
+		}
+		fromDoc = fromV.Order
+	}
+
+	changes, err := jsondiff.Values(fromDoc, toV.Order)
+	if err != nil {
+		s.log.Error("order diff failed", slog.String("order_uid", uid), slog.Any("err", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if changes == nil {
+		changes = []jsondiff.Change{}
+	}
+	s.respondJSON(w, http.StatusOK, diffResponse{OrderUID: uid, From: from, To: toV.Version, Changes: changes})
+}
+
+func (s *Server) versionError(w http.ResponseWriter, uid string, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "version not found", http.StatusNotFound)
+		return
+	}
+	s.log.Error("order version failed", slog.String("order_uid", uid), slog.Any("err", err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+// versionParam разбирает номер версии; пусто — 0 (значение по умолчанию).
+func versionParam(v string) (int, bool) {
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil && n > 0
+}
@@ -29,3 +29,18 @@ func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
+
+// handleLoaderStats — GET /debug/loader: счётчики загрузок заказов из БД на
+// промахах кэша. Без токена: кроме двух счётчиков здесь ничего нет.
+func (s *Server) handleLoaderStats(w http.ResponseWriter, _ *http.Request) {
+	s.respondJSON(w, http.StatusOK, s.loader.Stats())
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "warming up", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ready"))
+}
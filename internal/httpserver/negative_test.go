@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// emptyStore — БД, где заказ появляется только после saved.
+type emptyStore struct {
+	OrderStore
+	calls atomic.Int32
+	saved atomic.Bool
+}
+
+func (f *emptyStore) GetOrder(_ context.Context, id string) (storage.OrderWithMeta, error) {
+	f.calls.Add(1)
+	if !f.saved.Load() {
+		return storage.OrderWithMeta{}, storage.ErrNotFound
+	}
+	return storage.OrderWithMeta{Order: domain.Order{OrderUID: id}}, nil
+}
+
+func TestNegativeCache(t *testing.T) {
+	store := &emptyStore{}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{
+		CacheSize: 10, CacheTTL: time.Minute,
+		NegativeTTL: time.Minute,
+	})
+
+	rec := getOrder(t, s.mux, "ord_missing")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("X-Source") != "miss" {
+		t.Fatalf("first miss: %d %q, want 404 miss", rec.Code, rec.Header().Get("X-Source"))
+	}
+
+	// повторный 404 — из негативного кэша, без запроса в БД
+	rec = getOrder(t, s.mux, "ord_missing")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("X-Source") != "cache-miss" {
+		t.Fatalf("second miss: %d %q, want 404 cache-miss", rec.Code, rec.Header().Get("X-Source"))
+	}
+	if n := store.calls.Load(); n != 1 {
+		t.Fatalf("store calls = %d, want 1", n)
+	}
+
+	// заказ записан — отметка снимается, следующий запрос идёт в БД
+	s.InvalidateOrder("ord_missing")
+	if rec = getOrder(t, s.mux, "ord_missing"); rec.Header().Get("X-Source") != "miss" {
+		t.Fatalf("after invalidation: X-Source %q, want miss", rec.Header().Get("X-Source"))
+	}
+	if n := store.calls.Load(); n != 2 {
+		t.Fatalf("store calls after invalidation = %d, want 2", n)
+	}
+}
+
+func TestNegativeCacheOrderSavedAfterMiss(t *testing.T) {
+	store := &emptyStore{}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{
+		CacheSize: 10, CacheTTL: time.Minute,
+		NegativeTTL: time.Minute,
+	})
+
+	if rec := getOrder(t, s.mux, "ord_1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("before save: %d, want 404", rec.Code)
+	}
+
+	// консюмер записал заказ, SaveOrders прислал NOTIFY
+	store.saved.Store(true)
+	s.OrderChanged("ord_1")
+
+	rec := getOrder(t, s.mux, "ord_1")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Source") != "db" {
+		t.Fatalf("after save: %d %q, want 200 from db", rec.Code, rec.Header().Get("X-Source"))
+	}
+}
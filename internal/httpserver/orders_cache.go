@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// storeOrder кладёт заказ в кэш и снимает отметку «нет такого заказа».
+func (s *Server) storeOrder(e cachedOrder) { s.storeOrderTTL(e, 0) }
+
+// storeOrderTTL — storeOrder со своим сроком жизни записи (0 — TTL кэша).
+func (s *Server) storeOrderTTL(e cachedOrder, ttl time.Duration) {
+	s.cache.SetWithTTL(e.Order.OrderUID, e, ttl)
+	if s.missing != nil {
+		s.missing.Delete(e.Order.OrderUID)
+	}
+}
+
+// cacheOrders кладёт заказы в кэш вместе с готовыми ответами.
+func (s *Server) cacheOrders(orders []storage.OrderWithMeta) {
+	for _, o := range orders {
+		e, err := newCachedOrder(o)
+		if err != nil {
+			s.log.Warn("encode order failed", slog.String("order_uid", o.OrderUID), slog.Any("err", err))
+			continue
+		}
+		s.storeOrder(e)
+	}
+}
+
+// rememberMissing запоминает, что заказа id в БД нет (негативный кэш).
+func (s *Server) rememberMissing(id string) {
+	if s.missing != nil {
+		s.missing.Set(id, struct{}{})
+	}
+}
+
+func (s *Server) knownMissing(id string) bool {
+	if s.missing == nil {
+		return false
+	}
+	_, ok := s.missing.Get(id)
+	return ok
+}
+
+// PurgeCache очищает кэш заказов и негативный кэш; начатые загрузки
+// в кэш уже ничего не положат.
+func (s *Server) PurgeCache() {
+	s.loader.ForgetAll()
+	s.cache.Purge()
+	if s.missing != nil {
+		s.missing.Purge()
+	}
+}
+
+// InvalidateOrder сбрасывает всё, что кэш знает о заказе: сам заказ и
+// отметку о его отсутствии. Вызывается, когда заказ записан или изменён.
+// Загрузка, начатая раньше, могла прочитать старую строку: она отвязывается
+// до удаления, чтобы не положить её в кэш после него.
+func (s *Server) InvalidateOrder(id string) {
+	s.loader.Forget(id)
+	s.cache.Delete(id)
+	if s.missing != nil {
+		s.missing.Delete(id)
+	}
+}
+
+// OrderChanged — заказ изменён в БД. Запись кэша сбрасывается сразу; если
+// заказ был в кэше, он перечитывается в фоне, чтобы следующий запрос не
+// уходил в БД.
+func (s *Server) OrderChanged(id string) {
+	_, _, cached := s.cache.Peek(id)
+	s.InvalidateOrder(id)
+	if cached {
+		s.refreshOrder(id)
+	}
+}
+
+// refreshOrder перечитывает заказ в кэш в фоне; одновременные обновления
+// одного заказа склеиваются загрузчиком. После InvalidateOrder это всегда
+// новая загрузка.
+func (s *Server) refreshOrder(id string) {
+	go func() {
+		if _, _, err := s.loader.Load(context.Background(), id); err != nil {
+			s.log.Warn("cache refresh failed", slog.String("order_uid", id), slog.Any("err", err))
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// changingStore отдаёт заказ с текущим track; первая загрузка ждёт gate —
+// так она «прочитала строку» до изменения, а вернулась после него.
+type changingStore struct {
+	OrderStore
+	gate  chan struct{}
+	track atomic.Value
+	calls atomic.Int32
+}
+
+func (f *changingStore) GetOrder(_ context.Context, id string) (storage.OrderWithMeta, error) {
+	track := f.track.Load().(string)
+	if f.calls.Add(1) == 1 {
+		<-f.gate
+	}
+	return storage.OrderWithMeta{Order: domain.Order{OrderUID: id, TrackNumber: track}}, nil
+}
+
+func newChangingServer(t *testing.T) (*Server, *changingStore) {
+	t.Helper()
+	store := &changingStore{gate: make(chan struct{})}
+	store.track.Store("WBOLD")
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{CacheSize: 10, CacheTTL: time.Hour})
+	return s, store
+}
+
+// startLoad запускает загрузку ord_1 и ждёт, пока она дойдёт до БД.
+func startLoad(s *Server, store *changingStore) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = s.loader.Load(context.Background(), "ord_1")
+	}()
+	for store.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func cachedTrack(t *testing.T, s *Server) string {
+	t.Helper()
+	e, _, ok := s.cache.Peek("ord_1")
+	if !ok {
+		return ""
+	}
+	return e.Order.TrackNumber
+}
+
+func TestInvalidateDropsInFlightLoad(t *testing.T) {
+	s, store := newChangingServer(t)
+	done := startLoad(s, store)
+
+	store.track.Store("WBNEW")
+	s.OrderChanged("ord_1") // заказа в кэше нет — только сброс
+	close(store.gate)
+	<-done
+
+	if got := cachedTrack(t, s); got != "" {
+		t.Fatalf("cached %q after invalidation, want nothing", got)
+	}
+}
+
+func TestOrderChangedRefreshStartsNewLoad(t *testing.T) {
+	s, store := newChangingServer(t)
+	s.cache.Set("ord_1", cachedOrder{Order: storage.OrderWithMeta{Order: domain.Order{OrderUID: "ord_1", TrackNumber: "WBOLD"}}})
+	done := startLoad(s, store) // например, фоновое обновление просроченной записи
+
+	store.track.Store("WBNEW")
+	s.OrderChanged("ord_1")
+	// обновление не должно ждать старую загрузку
+	deadline := time.Now().Add(2 * time.Second)
+	for cachedTrack(t, s) != "WBNEW" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(store.gate)
+	<-done
+
+	if got := cachedTrack(t, s); got != "WBNEW" {
+		t.Fatalf("cached %q, want WBNEW", got)
+	}
+	if n := store.calls.Load(); n != 2 {
+		t.Fatalf("store calls = %d, want 2 (refresh must not join the old load)", n)
+	}
+}
+
+func TestPurgeCacheDropsInFlightLoad(t *testing.T) {
+	s, store := newChangingServer(t)
+	s.cache.Set("ord_2", cachedOrder{Order: storage.OrderWithMeta{Order: domain.Order{OrderUID: "ord_2"}}})
+	done := startLoad(s, store)
+
+	s.PurgeCache() // разрыв LISTEN: уведомления потеряны
+	close(store.gate)
+	<-done
+
+	if _, _, ok := s.cache.Peek("ord_2"); ok {
+		t.Fatal("purge must drop cached orders")
+	}
+	if got := cachedTrack(t, s); got != "" {
+		t.Fatalf("cached %q after purge, want nothing", got)
+	}
+}
@@ -2,36 +2,96 @@ package httpserver
 
 import (
 	"context"
+	"expvar"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/sillkiw/wb-l0/internal/cache"
-	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/dlq"
+	"github.com/sillkiw/wb-l0/internal/storage"
 )
 
 type OrderStore interface {
-	GetOrder(ctx context.Context, orderUID string) (domain.Order, error)
+	GetOrder(ctx context.Context, orderUID string) (storage.OrderWithMeta, error)
+	RecentOrders(ctx context.Context, after storage.OrderCursor, limit int) ([]storage.OrderWithMeta, error)
+	ListOrders(ctx context.Context, f storage.OrderFilter, after storage.OrderCursor, limit int) ([]storage.OrderWithMeta, error)
+	LookupOrders(ctx context.Context, field storage.LookupField, value string, limit int) ([]storage.OrderWithMeta, error)
+	OrderHistory(ctx context.Context, orderUID string, limit int) ([]storage.OrderVersion, error)
+	OrderVersion(ctx context.Context, orderUID string, version int) (storage.OrderVersion, error)
+	OrderAsOf(ctx context.Context, orderUID string, t time.Time) (storage.OrderVersion, error)
+	OrderVersionTS(ctx context.Context, orderUID string) (time.Time, error)
 }
 
+// DeadLetterStore — просмотр и разбор DLQ, хранящегося в Postgres.
+type DeadLetterStore interface {
+	List(ctx context.Context, f dlq.ListFilter) ([]dlq.DeadLetter, error)
+	Get(ctx context.Context, id int64) (dlq.DeadLetter, error)
+	SetStatus(ctx context.Context, id int64, status string) (dlq.DeadLetter, error)
+}
+
+// orderLoadTimeout ограничивает одну загрузку заказа из БД на промахе кэша.
+const orderLoadTimeout = 3 * time.Second
+
 type Options struct {
-	CacheSize    int
-	CacheTTL     time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	CacheSize   int
+	CacheTTL    time.Duration
+	CacheShards int // >1 — cache.Sharded вместо одного LRU
+
+	// Ограничение кэша по памяти (оценка, см. orderCost); 0 — только по числу записей.
+	// С CacheMaxBytes > 0 CacheSize = 0 снимает ограничение по числу записей.
+	CacheMaxBytes     int64
+	CacheMaxItemBytes int64 // заказы дороже не кэшируются; 0 — бюджет шарда
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// Просроченные записи: сколько после TTL отдавать сразу, обновляя в фоне
+	// (StaleWhileRevalidate), и сколько — только если БД ответила ошибкой (StaleIfError).
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+
+	// Негативный кэш: id, которых нет в БД. NegativeTTL <= 0 — выключен.
+	NegativeTTL  time.Duration
+	NegativeSize int
+
+	// Срок жизни заказов, положенных FeedCache: консюмер может их ещё
+	// отклонить. 0 — CacheTTL.
+	FeedTTL time.Duration
+
+	CacheControl string // заголовок Cache-Control для /order/{id}; пусто — не отдавать
+
+	AdminToken string // пусто — /admin/* выключен
+
+	DeadLetters DeadLetterStore // nil или пустой AdminToken — API /api/v1/dead-letters выключен
 }
 
 type Server struct {
-	mux   *http.ServeMux
-	log   *slog.Logger
-	ui    http.FileSystem
-	store OrderStore
-	cache *cache.LRU[domain.Order]
+	mux    *http.ServeMux
+	log    *slog.Logger
+	ui     http.FileSystem
+	store  OrderStore
+	cache  cache.Cache[cachedOrder]
+	loader *cache.Loader[cachedOrder]
+
+	missing *cache.LRU[struct{}] // nil — негативный кэш выключен
+	dead    DeadLetterStore
+
+	cacheSize    int
+	cacheControl string
+	feedTTL      time.Duration
+
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+
+	adminToken string
+	warming    atomic.Bool // идёт прогрев: при старте или через /admin
+	ready      atomic.Bool // true после прогрева кэша
 }
 
 func New(log *slog.Logger, store OrderStore, ui http.FileSystem, opts Options) *Server {
-	if opts.CacheSize <= 0 {
+	if opts.CacheSize <= 0 && opts.CacheMaxBytes <= 0 {
 		opts.CacheSize = 1000
 	}
 	if opts.CacheTTL <= 0 {
@@ -43,8 +103,40 @@ func New(log *slog.Logger, store OrderStore, ui http.FileSystem, opts Options) *
 		log:   log,
 		ui:    ui,
 		store: store,
-		cache: cache.NewLRU[domain.Order](opts.CacheSize, opts.CacheTTL),
+		dead:  opts.DeadLetters,
+
+		cacheSize:    opts.CacheSize,
+		cacheControl: opts.CacheControl,
+		feedTTL:      opts.FeedTTL,
+
+		staleWhileRevalidate: opts.StaleWhileRevalidate,
+		staleIfError:         opts.StaleIfError,
+		adminToken:           opts.AdminToken,
+	}
+	var cost cache.CostOptions[cachedOrder]
+	if opts.CacheMaxBytes > 0 {
+		cost = cache.CostOptions[cachedOrder]{
+			Cost:        orderCost,
+			MaxCost:     opts.CacheMaxBytes,
+			MaxItemCost: opts.CacheMaxItemBytes,
+		}
+	}
+	if opts.CacheShards > 1 {
+		s.cache = cache.NewShardedWithCost(opts.CacheShards, opts.CacheSize, opts.CacheTTL, cost)
+	} else {
+		s.cache = cache.NewLRUWithCost(opts.CacheSize, opts.CacheTTL, cost)
 	}
+	if grace := max(opts.StaleWhileRevalidate, opts.StaleIfError); grace > 0 {
+		s.cache.SetStaleGrace(grace)
+	}
+	if opts.NegativeTTL > 0 {
+		if opts.NegativeSize <= 0 {
+			opts.NegativeSize = 10000
+		}
+		s.missing = cache.NewLRU[struct{}](opts.NegativeSize, opts.NegativeTTL)
+	}
+	s.loader = cache.NewLoader(s.loadOrder, orderLoadTimeout)
+	s.loader.SetOnLoad(s.orderLoaded)
 	s.routes()
 	return s
 }
@@ -58,5 +150,30 @@ func (s *Server) routes() {
 
 	// API
 	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("GET /debug/loader", s.handleLoaderStats)
 	s.mux.HandleFunc("GET /order/", s.handleGetOrder) // /api/orders/{order_uid}
+	s.mux.HandleFunc("GET /api/v1/orders", s.handleListOrders)
+	s.mux.HandleFunc("GET /api/v1/lookup", s.handleLookup)
+	s.mux.HandleFunc("GET /api/v1/orders/{uid}/history", s.handleOrderHistory)
+	s.mux.HandleFunc("GET /api/v1/orders/{uid}/diff", s.handleOrderDiff)
+
+	// Админка кэша
+	if s.adminToken != "" {
+		s.mux.HandleFunc("GET /admin/cache/stats", s.withAdmin(s.handleCacheStats))
+		s.mux.HandleFunc("GET /admin/cache/orders/{id}", s.withAdmin(s.handleCacheGetKey))
+		s.mux.HandleFunc("DELETE /admin/cache/orders/{id}", s.withAdmin(s.handleCacheDeleteKey))
+		s.mux.HandleFunc("POST /admin/cache/purge", s.withAdmin(s.handleCachePurge))
+		s.mux.HandleFunc("POST /admin/cache/warmup", s.withAdmin(s.handleCacheWarmup))
+		// expvar отдаёт cmdline и memstats — тоже только админке
+		s.mux.HandleFunc("GET /debug/vars", s.withAdmin(expvar.Handler().ServeHTTP))
+	}
+
+	// DLQ: сырые payload'ы и смена статуса — только с токеном админки
+	if s.dead != nil && s.adminToken != "" {
+		s.mux.HandleFunc("GET /api/v1/dead-letters", s.withAdmin(s.handleListDeadLetters))
+		s.mux.HandleFunc("GET /api/v1/dead-letters/{id}", s.withAdmin(s.handleGetDeadLetter))
+		s.mux.HandleFunc("POST /api/v1/dead-letters/{id}/resolve", s.withAdmin(s.handleSetDeadLetterStatus(dlq.StatusResolved)))
+		s.mux.HandleFunc("POST /api/v1/dead-letters/{id}/ignore", s.withAdmin(s.handleSetDeadLetterStatus(dlq.StatusIgnored)))
+	}
 }
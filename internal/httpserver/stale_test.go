@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// fakeStore реализует только GetOrder; остальные методы OrderStore не вызываются.
+type fakeStore struct {
+	OrderStore
+	fail  atomic.Bool
+	calls atomic.Int32
+}
+
+func (f *fakeStore) GetOrder(_ context.Context, id string) (storage.OrderWithMeta, error) {
+	f.calls.Add(1)
+	if f.fail.Load() {
+		return storage.OrderWithMeta{}, errors.New("db is down")
+	}
+	return storage.OrderWithMeta{Order: domain.Order{OrderUID: id}}, nil
+}
+
+func getOrder(t *testing.T, h http.Handler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+id, nil))
+	return rec
+}
+
+func TestGetOrderServesStale(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("if-error", func(t *testing.T) {
+		store := &fakeStore{}
+		s := New(log, store, nil, Options{CacheTTL: time.Millisecond, StaleIfError: time.Hour})
+		if rec := getOrder(t, s.mux, "ord_1"); rec.Code != http.StatusOK || rec.Header().Get("X-Source") != "db" {
+			t.Fatalf("first: %d %q", rec.Code, rec.Header().Get("X-Source"))
+		}
+		time.Sleep(5 * time.Millisecond)
+		store.fail.Store(true)
+
+		rec := getOrder(t, s.mux, "ord_1")
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Source") != "stale" {
+			t.Fatalf("db down: %d %q, want 200 stale", rec.Code, rec.Header().Get("X-Source"))
+		}
+		if w := rec.Header().Get("Warning"); w != `111 - "Revalidation Failed"` {
+			t.Fatalf("Warning = %q", w)
+		}
+		if rec := getOrder(t, s.mux, "ord_2"); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("uncached order with db down: %d, want 500", rec.Code)
+		}
+	})
+
+	t.Run("while-revalidate", func(t *testing.T) {
+		store := &fakeStore{}
+		s := New(log, store, nil, Options{CacheTTL: time.Millisecond, StaleWhileRevalidate: time.Hour})
+		getOrder(t, s.mux, "ord_1")
+		time.Sleep(5 * time.Millisecond)
+
+		rec := getOrder(t, s.mux, "ord_1")
+		if rec.Header().Get("X-Source") != "stale" || rec.Header().Get("Warning") != `110 - "Response is Stale"` {
+			t.Fatalf("stale read: %q %q", rec.Header().Get("X-Source"), rec.Header().Get("Warning"))
+		}
+		// фоновое обновление кладёт свежую копию
+		deadline := time.Now().Add(time.Second)
+		for store.calls.Load() < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if store.calls.Load() < 2 {
+			t.Fatal("stale read did not trigger a background refresh")
+		}
+	})
+}
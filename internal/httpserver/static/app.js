@@ -9,8 +9,12 @@
   const resultEl = $('result');
   const summaryEl = $('summary');
   const itemsEl = $('items');
+  const sourceEl = $('source');
+  const sourceBody = $('sourceBody');
   const historyWrap = $('history');
   const historyList = $('historyList');
+  const matchesWrap = $('matches');
+  const matchesList = $('matchesList');
 
   // история только в текущей сессии
   const HISTORY_KEY = 'order_search_history_session';
@@ -45,6 +49,45 @@
     });
   };
 
+  // тип идентификатора по префиксу; всё остальное считаем order_uid
+  const LOOKUP_PREFIXES = [
+    ['WB', 'track_number'],
+    ['txn_', 'transaction'],
+    ['req_', 'request_id'],
+    ['RID-', 'rid'],
+  ];
+  const detectKind = (id) => {
+    const hit = LOOKUP_PREFIXES.find(([p]) => id.startsWith(p));
+    return hit ? hit[1] : 'order_uid';
+  };
+  const KIND_LABELS = {
+    order_uid: 'order_id',
+    track_number: 'трек-номер',
+    transaction: 'транзакция',
+    request_id: 'request ID',
+    rid: 'rid товара',
+  };
+
+  // несколько заказов по одному идентификатору — показываем первый, остальные списком
+  const renderMatches = (orders, current) => {
+    if (!orders || orders.length < 2) { matchesWrap.hidden = true; matchesList.innerHTML = ''; return; }
+    matchesWrap.hidden = false;
+    matchesList.innerHTML = '';
+    orders.forEach(o => {
+      const span = document.createElement('span');
+      span.className = 'pill';
+      span.textContent = o.order_uid;
+      if (o.order_uid === current) span.style.fontWeight = '600';
+      span.onclick = () => {
+        renderSummary(o);
+        renderItems(o);
+        resetSource(o.order_uid);
+        renderMatches(orders, o.order_uid);
+      };
+      matchesList.appendChild(span);
+    });
+  };
+
   // начальная история
   renderHistory(JSON.parse(sessionStorage.getItem(HISTORY_KEY) || '[]'));
 
@@ -118,6 +161,39 @@
     summaryEl.hidden = cells.length === 0;
   };
 
+  // откуда пришёл заказ: ?include=meta запрашивается только при раскрытии
+  // панели — обычный ответ отдаётся из кэша готовым телом и с 304
+  const resetSource = (uid) => {
+    sourceEl.open = false;
+    sourceEl.hidden = !uid;
+    sourceEl.dataset.uid = uid || '';
+    sourceBody.innerHTML = '';
+  };
+
+  const loadSource = async () => {
+    const uid = sourceEl.dataset.uid;
+    if (!uid || sourceBody.innerHTML) return;
+    sourceBody.textContent = 'Загрузка…';
+    try {
+      const resp = await fetch(`/order/${encodeURIComponent(uid)}?include=meta`, { headers: { 'Accept': 'application/json' } });
+      if (!resp.ok) throw new Error(`HTTP ${resp.status}`);
+      const meta = (await resp.json())._meta || {};
+      if (sourceEl.dataset.uid !== uid) return; // пока ждали, выбрали другой заказ
+
+      const cells = [];
+      const add = (k, v) => { if (v !== '' && v != null) cells.push(`<div class="k">${k}</div><div>${v}</div>`); };
+      if (meta.source_topic) {
+        add('Сообщение', `<code class="mono">${esc(meta.source_topic)}:${esc(meta.source_partition)}:${esc(meta.source_offset)}</code>`);
+      }
+      if (meta.ingested_at) add('Записан', fmtDateIso(meta.ingested_at));
+      if (meta.updated_at)  add('Обновлён', fmtDateIso(meta.updated_at));
+      sourceBody.innerHTML = cells.length ? cells.join('') : '<div class="k">Нет данных</div>';
+    } catch (err) {
+      if (sourceEl.dataset.uid === uid) sourceBody.textContent = `Не удалось загрузить: ${err.message || err}`;
+    }
+  };
+  sourceEl.addEventListener('toggle', () => { if (sourceEl.open) loadSource(); });
+
   const renderItems = (data) => {
     const items = Array.isArray(data.items) ? data.items : (Array.isArray(data.order_items) ? data.order_items : []);
     if (!items.length) { itemsEl.hidden = true; itemsEl.innerHTML = ''; return; }
@@ -151,6 +227,10 @@
     e.preventDefault();
     const id = (input.value || '').trim();
     if (!id) { setStatus('Введите корректный order_id.'); return; }
+    const kind = detectKind(id);
+    const url = kind === 'order_uid'
+      ? `/order/${encodeURIComponent(id)}`
+      : `/api/v1/lookup?${kind}=${encodeURIComponent(id)}`;
 
     btn.disabled = true;
     setStatus('Идёт запрос…');
@@ -160,13 +240,15 @@
     itemsEl.hidden = true;
     summaryEl.innerHTML = '';
     itemsEl.innerHTML = '';
+    resetSource('');
+    renderMatches(null);
 
     const t0 = performance.now();
     const ac = new AbortController();
     const timeout = setTimeout(() => ac.abort(), 7000); // 7s
 
     try {
-      const resp = await fetch(`/order/${encodeURIComponent(id)}`, {
+      const resp = await fetch(url, {
         headers: { 'Accept': 'application/json' },
         signal: ac.signal,
       });
@@ -176,7 +258,7 @@
       const dt = `${(performance.now() - t0).toFixed(0)} ms`;
 
       if (resp.status === 404) {
-        setStatus('Заказ не найден.');
+        setStatus(`Заказ не найден (${KIND_LABELS[kind]}).`);
         setMeta([src ? `Источник: ${src}` : null, `Время: ${dt}`].filter(Boolean).join(' · '));
         return;
       }
@@ -187,12 +269,16 @@
         return;
       }
 
-      const data = await resp.json();
+      const body = await resp.json();
+      const orders = kind === 'order_uid' ? [body] : (body.items || []);
+      const data = orders[0] || {};
       renderSummary(data);
       renderItems(data);
+      resetSource(data.order_uid);
+      renderMatches(orders, data.order_uid);
 
       resultEl.hidden = false;
-      setStatus('Готово.');
+      setStatus(kind === 'order_uid' ? 'Готово.' : `Готово: поиск по полю «${KIND_LABELS[kind]}», заказов: ${orders.length}.`);
       setMeta([src ? `Источник: ${src}` : null, `Время: ${dt}`].filter(Boolean).join(' · '));
 
       keepHistory(id);
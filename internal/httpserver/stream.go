@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/storage"
+	"github.com/sillkiw/wb-l0/internal/validation"
+)
+
+// MessageSource — читатель топика заказов (kafka.Consumer).
+type MessageSource interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// FeedCache читает топик заказов до отмены ctx и кладёт валидные заказы
+// в кэш — свежий заказ отдаётся из памяти, не дожидаясь первого запроса.
+// В БД ничего не пишется: это делает консюмер, а невалидные сообщения он же
+// отправит в DLQ, здесь они просто пропускаются. Версия старше закэшированной
+// или записанной в БД тоже пропускается: консюмер её не запишет (ErrStale).
+// Заказ из топика живёт в кэше FeedTTL: если консюмер отклонит его по другой
+// причине, NOTIFY не придёт, и запись должна уйти сама.
+func (s *Server) FeedCache(ctx context.Context, src MessageSource) {
+	for {
+		msg, err := src.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			s.log.Error("cache feed: kafka read failed", slog.Any("err", err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if o, ok := s.decodeStreamOrder(msg); ok {
+			s.feedOrder(ctx, o)
+		}
+		if err := src.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			s.log.Warn("cache feed: commit failed", slog.Any("err", err))
+		}
+	}
+}
+
+// feedOrder кладёт заказ из топика в кэш, если ни в кэше, ни в БД нет
+// версии новее. БД спрашивается только на промахе кэша.
+func (s *Server) feedOrder(ctx context.Context, o storage.OrderWithMeta) {
+	e, err := newCachedOrder(o)
+	if err != nil {
+		return
+	}
+	known, ok := s.knownVersion(ctx, o.OrderUID)
+	if !ok {
+		return
+	}
+	if known.After(e.Version) {
+		s.log.Debug("cache feed: skip stale order",
+			slog.String("order_uid", o.OrderUID),
+			slog.Time("version", e.Version),
+			slog.Time("known_version", known),
+		)
+		return
+	}
+	s.storeOrderTTL(e, s.feedTTL)
+}
+
+// knownVersion — версия заказа из кэша, а если его там нет — из БД (нулевая,
+// если заказа в БД нет). ok == false — БД не ответила: без проверки заказ
+// в кэш не кладётся.
+func (s *Server) knownVersion(ctx context.Context, id string) (time.Time, bool) {
+	if cur, _, ok := s.cache.Peek(id); ok {
+		return cur.Version, true
+	}
+	ctx, cancel := context.WithTimeout(ctx, orderLoadTimeout)
+	defer cancel()
+	v, err := s.store.OrderVersionTS(ctx, id)
+	switch {
+	case err == nil:
+		return v, true
+	case errors.Is(err, storage.ErrNotFound):
+		return time.Time{}, true
+	default:
+		s.log.Warn("cache feed: version check failed", slog.String("order_uid", id), slog.Any("err", err))
+		return time.Time{}, false
+	}
+}
+
+func (s *Server) decodeStreamOrder(msg kafka.Message) (storage.OrderWithMeta, bool) {
+	var o domain.Order
+	if err := validation.DecodeStrict(msg.Value, &o); err != nil {
+		s.log.Debug("cache feed: skip undecodable message",
+			slog.Int("partition", msg.Partition),
+			slog.Int64("offset", msg.Offset),
+			slog.Any("err", err),
+		)
+		return storage.OrderWithMeta{}, false
+	}
+	if verrs := validation.ValidateOrder(o); verrs != nil {
+		s.log.Debug("cache feed: skip invalid order",
+			slog.String("order_uid", o.OrderUID),
+			slog.Any("err", verrs),
+		)
+		return storage.OrderWithMeta{}, false
+	}
+
+	// в БД заказ ещё не записан: известны только источник и версия
+	part, off := msg.Partition, msg.Offset
+	version := msg.OriginTimestamp().UTC()
+	meta := &storage.Meta{
+		Version:         &version,
+		SourceTopic:     msg.Topic,
+		SourcePartition: &part,
+		SourceOffset:    &off,
+		SourceKey:       string(msg.Key),
+	}
+	return storage.OrderWithMeta{Order: o, Meta: meta}, true
+}
@@ -0,0 +1,105 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/generator"
+	"github.com/sillkiw/wb-l0/internal/kafka"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// sliceSource отдаёт msgs по порядку, затем отменяет контекст FeedCache.
+type sliceSource struct {
+	msgs   []kafka.Message
+	cancel context.CancelFunc
+}
+
+func (f *sliceSource) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(f.msgs) == 0 {
+		f.cancel()
+		return kafka.Message{}, context.Canceled
+	}
+	m := f.msgs[0]
+	f.msgs = f.msgs[1:]
+	return m, nil
+}
+
+func (f *sliceSource) CommitMessages(context.Context, ...kafka.Message) error { return nil }
+
+// versionStore отдаёт version_ts заказа из БД; нулевое version — заказа нет.
+type versionStore struct {
+	OrderStore
+	version time.Time
+}
+
+func (f *versionStore) OrderVersionTS(context.Context, string) (time.Time, error) {
+	if f.version.IsZero() {
+		return time.Time{}, storage.ErrNotFound
+	}
+	return f.version, nil
+}
+
+// feed прогоняет сообщения через FeedCache.
+func feed(s *Server, msgs ...kafka.Message) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.FeedCache(ctx, &sliceSource{cancel: cancel, msgs: msgs})
+}
+
+func orderMessage(t *testing.T, v any, ts time.Time) kafka.Message {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kafka.Message{Topic: "orders", Value: b, Timestamp: ts}
+}
+
+func TestFeedCacheSkipsOlderVersion(t *testing.T) {
+	o := generator.NewFakeOrder(1)
+	newer, older := o, o
+	newer.TrackNumber, older.TrackNumber = "WBNEW", "WBOLD"
+	t0 := time.Now().Add(-time.Minute)
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &versionStore{}, nil, Options{CacheSize: 10, CacheTTL: time.Hour})
+	feed(s,
+		orderMessage(t, newer, t0.Add(time.Second)),
+		orderMessage(t, older, t0), // пришло позже, но версия старше
+	)
+
+	e, _, ok := s.cache.Peek(o.OrderUID)
+	if !ok {
+		t.Fatal("order must be cached")
+	}
+	if e.Order.TrackNumber != "WBNEW" {
+		t.Fatalf("cached track_number = %q, want newer version WBNEW", e.Order.TrackNumber)
+	}
+}
+
+func TestFeedCacheChecksDBOnMiss(t *testing.T) {
+	o := generator.NewFakeOrder(1)
+	t0 := time.Now().Add(-time.Minute)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// в кэше пусто, а в БД уже версия новее — например, повтор из dlq-replay
+	s := New(log, &versionStore{version: t0.Add(time.Second)}, nil, Options{CacheSize: 10, CacheTTL: time.Hour})
+	feed(s, orderMessage(t, o, t0))
+	if _, _, ok := s.cache.Peek(o.OrderUID); ok {
+		t.Fatal("older version than in DB must not be cached")
+	}
+
+	// в БД заказа нет — кладётся, но только на FeedTTL
+	s = New(log, &versionStore{}, nil, Options{CacheSize: 10, CacheTTL: time.Hour, FeedTTL: time.Minute})
+	feed(s, orderMessage(t, o, t0))
+	_, exp, ok := s.cache.Peek(o.OrderUID)
+	if !ok {
+		t.Fatal("new order must be cached")
+	}
+	if exp.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("fed order expires at %v, want within FeedTTL", exp)
+	}
+}
@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// ErrWarmupRunning — прогрев кэша уже идёт.
+var ErrWarmupRunning = errors.New("cache warmup already running")
+
+// WarmCache загружает в кэш до limit последних заказов порциями по batch штук.
+// limit ограничивается числом записей кэша (если оно задано): более старые
+// заказы всё равно вытеснили бы новые.
+// Заказы кладутся от старых к новым, чтобы самые свежие оказались в голове
+// LRU и вытеснялись последними. Возвращает число загруженных заказов (в том
+// числе при ошибке: прочитанное до ошибки всё равно кладётся в кэш).
+// Одновременно идёт только один прогрев: если он уже идёт (например, запущен
+// через /admin), WarmCache сразу возвращает ErrWarmupRunning.
+func (s *Server) WarmCache(ctx context.Context, limit, batch int) (int, error) {
+	if !s.warming.CompareAndSwap(false, true) {
+		return 0, ErrWarmupRunning
+	}
+	defer s.warming.Store(false)
+	return s.warmCache(ctx, limit, batch)
+}
+
+// warmCache — WarmCache без проверки s.warming: её делает вызывающий.
+func (s *Server) warmCache(ctx context.Context, limit, batch int) (int, error) {
+	if s.cacheSize > 0 && limit > s.cacheSize {
+		limit = s.cacheSize
+	}
+	if batch <= 0 {
+		batch = 500
+	}
+
+	var (
+		cursor storage.OrderCursor
+		all    []storage.OrderWithMeta // от новых к старым
+	)
+	defer func() {
+		slices.Reverse(all)
+		s.cacheOrders(all)
+	}()
+	for len(all) < limit {
+		n := min(batch, limit-len(all))
+		orders, err := s.store.RecentOrders(ctx, cursor, n)
+		if err != nil {
+			return len(all), err
+		}
+		all = append(all, orders...)
+		loaded := len(all)
+		s.log.Info("cache warmup progress",
+			slog.Int("loaded", loaded),
+			slog.Int("limit", limit),
+		)
+		if len(orders) < n {
+			break // в БД больше нет заказов
+		}
+		last := orders[len(orders)-1]
+		cursor = storage.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+	}
+	return len(all), nil
+}
+
+// SetReady переключает ответ /readyz.
+func (s *Server) SetReady(v bool) { s.ready.Store(v) }
@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+	"github.com/sillkiw/wb-l0/internal/storage"
+)
+
+// recentStore отдаёт orders (от новых к старым) как RecentOrders.
+type recentStore struct {
+	OrderStore
+	orders []storage.OrderWithMeta
+}
+
+func (f *recentStore) RecentOrders(_ context.Context, after storage.OrderCursor, limit int) ([]storage.OrderWithMeta, error) {
+	start := 0
+	if !after.IsZero() {
+		for i, o := range f.orders {
+			if o.OrderUID == after.OrderUID {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+limit, len(f.orders))
+	return f.orders[start:end], nil
+}
+
+func TestWarmCacheNewestEvictedLast(t *testing.T) {
+	store := &recentStore{orders: []storage.OrderWithMeta{
+		{Order: domain.Order{OrderUID: "ord_3"}},
+		{Order: domain.Order{OrderUID: "ord_2"}},
+		{Order: domain.Order{OrderUID: "ord_1"}},
+	}}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{CacheSize: 3, CacheTTL: time.Hour})
+
+	n, err := s.WarmCache(context.Background(), 3, 2)
+	if err != nil || n != 3 {
+		t.Fatalf("WarmCache = %d, %v; want 3", n, err)
+	}
+	// новый заказ вытесняет самый старый из прогретых, а не самый свежий
+	s.cache.Set("ord_4", cachedOrder{Order: storage.OrderWithMeta{Order: domain.Order{OrderUID: "ord_4"}}})
+	if _, _, ok := s.cache.Peek("ord_1"); ok {
+		t.Fatal("oldest warmed order must be evicted first")
+	}
+	if _, _, ok := s.cache.Peek("ord_3"); !ok {
+		t.Fatal("newest warmed order must stay cached")
+	}
+}
+
+// blockingRecent держит RecentOrders, пока не закрыт gate.
+type blockingRecent struct {
+	OrderStore
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (f *blockingRecent) RecentOrders(context.Context, storage.OrderCursor, int) ([]storage.OrderWithMeta, error) {
+	close(f.started)
+	<-f.gate
+	return nil, nil
+}
+
+func TestWarmupRunsOnce(t *testing.T) {
+	store := &blockingRecent{started: make(chan struct{}), gate: make(chan struct{})}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, Options{CacheSize: 10, AdminToken: "secret"})
+
+	done := make(chan error)
+	go func() {
+		_, err := s.WarmCache(context.Background(), 10, 10) // прогрев при старте
+		done <- err
+	}()
+	<-store.started
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("admin warmup during startup warmup: %d, want 409", rec.Code)
+	}
+	if _, err := s.WarmCache(context.Background(), 10, 10); !errors.Is(err, ErrWarmupRunning) {
+		t.Fatalf("second WarmCache: err = %v, want ErrWarmupRunning", err)
+	}
+
+	close(store.gate)
+	if err := <-done; err != nil {
+		t.Fatalf("startup warmup: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package jsondiff сравнивает два JSON-документа поле за полем.
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Операции изменения поля.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Change — изменение одного поля. Path в том же виде, что и пути ошибок
+// валидации: "payment.amount", "items[0].price".
+type Change struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// Values сравнивает два значения, предварительно приведя их к JSON-модели
+// (map[string]any, []any, числа, строки). Nil — отсутствующий документ.
+func Values(from, to any) ([]Change, error) {
+	a, err := normalize(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := normalize(to)
+	if err != nil {
+		return nil, err
+	}
+	var out []Change
+	walk("", a, b, &out)
+	return out, nil
+}
+
+func normalize(v any) (any, error) {
+	if v == nil {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("jsondiff: marshal: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("jsondiff: unmarshal: %w", err)
+	}
+	return out, nil
+}
+
+// walk дописывает в out изменения между a и b. Объекты сравниваются по ключам
+// (в алфавитном порядке), массивы — по индексам.
+func walk(path string, a, b any, out *[]Change) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			x, inA := av[k]
+			y, inB := bv[k]
+			switch {
+			case !inA:
+				*out = append(*out, Change{Path: p, Op: OpAdd, To: y})
+			case !inB:
+				*out = append(*out, Change{Path: p, Op: OpRemove, From: x})
+			default:
+				walk(p, x, y, out)
+			}
+		}
+		return
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(av), len(bv)); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(av):
+				*out = append(*out, Change{Path: p, Op: OpAdd, To: bv[i]})
+			case i >= len(bv):
+				*out = append(*out, Change{Path: p, Op: OpRemove, From: av[i]})
+			default:
+				walk(p, av[i], bv[i], out)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, Change{Path: path, Op: OpReplace, From: a, To: b})
+	}
+}
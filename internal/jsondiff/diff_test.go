@@ -0,0 +1,51 @@
+package jsondiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	type item struct {
+		Price int    `json:"price"`
+		Name  string `json:"name,omitempty"`
+	}
+	type order struct {
+		UID   string         `json:"order_uid"`
+		Pay   map[string]int `json:"payment"`
+		Items []item         `json:"items"`
+	}
+
+	from := order{UID: "ord_1", Pay: map[string]int{"amount": 100}, Items: []item{{Price: 10, Name: "a"}, {Price: 20}}}
+	to := order{UID: "ord_1", Pay: map[string]int{"amount": 150, "fee": 5}, Items: []item{{Price: 12, Name: "a"}}}
+
+	got, err := Values(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Path: "items[0].price", Op: OpReplace, From: 10.0, To: 12.0},
+		{Path: "items[1]", Op: OpRemove, From: map[string]any{"price": 20.0}},
+		{Path: "payment.amount", Op: OpReplace, From: 100.0, To: 150.0},
+		{Path: "payment.fee", Op: OpAdd, To: 5.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff =\n%#v\nwant\n%#v", got, want)
+	}
+
+	if got, _ := Values(to, to); len(got) != 0 {
+		t.Fatalf("equal documents: %#v", got)
+	}
+
+	// нет исходной версии — все поля добавлены
+	got, _ = Values(nil, map[string]any{"a": 1, "b": "x"})
+	if len(got) != 2 || got[0].Op != OpAdd || got[0].Path != "a" || got[1].Path != "b" {
+		t.Fatalf("diff from nil = %#v", got)
+	}
+
+	// смена типа значения — замена целиком
+	got, _ = Values(map[string]any{"a": []int{1}}, map[string]any{"a": "x"})
+	if len(got) != 1 || got[0].Op != OpReplace || got[0].Path != "a" {
+		t.Fatalf("type change = %#v", got)
+	}
+}
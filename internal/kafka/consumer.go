@@ -18,6 +18,12 @@ type Message struct {
 	Offset    int64
 	Topic     string
 	Timestamp time.Time
+	Headers   []Header
+}
+
+type Header struct {
+	Key   string
+	Value []byte
 }
 
 func NewReader(brokers []string, topic string, groupID string) *Consumer {
@@ -31,20 +37,25 @@ func NewReader(brokers []string, topic string, groupID string) *Consumer {
 	return &Consumer{reader: reader}
 }
 
+// NewTailReader — читатель только свежих сообщений: новая группа начинает
+// с конца топика, коммиты отправляются в фоне раз в секунду.
+func NewTailReader(brokers []string, topic string, groupID string) *Consumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          topic,
+		GroupID:        groupID,
+		CommitInterval: time.Second,
+		StartOffset:    kafka.LastOffset,
+	})
+	return &Consumer{reader: reader}
+}
+
 func (c *Consumer) FetchMessage(ctx context.Context) (Message, error) {
 	msg, err := c.reader.FetchMessage(ctx)
 	if err != nil {
 		return Message{}, err
 	}
-
-	return Message{
-		Key:       msg.Key,
-		Value:     msg.Value,
-		Partition: msg.Partition,
-		Offset:    msg.Offset,
-		Topic:     msg.Topic,
-		Timestamp: msg.Time,
-	}, nil
+	return convert(msg), nil
 }
 
 func (c *Consumer) CommitMessages(ctx context.Context, msgs ...Message) error {
@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+)
+
+// Заголовки, которыми сообщение обрастает на пути ретраев.
+const (
+	// HeaderAttempt — номер попытки обработки (1 — исходное сообщение).
+	HeaderAttempt = "x-attempt"
+
+	// Координаты исходного сообщения в основном топике.
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderOriginalTimestamp = "x-original-timestamp" // unix ms
+
+	// HeaderFirstFailedAt — время первой неудачной попытки (unix ms).
+	HeaderFirstFailedAt = "x-first-failed-at"
+
+	// HeaderReplayedFrom — координаты DLQ-сообщения (topic:partition:offset),
+	// из которого сообщение переиграно утилитой dlq-replay.
+	HeaderReplayedFrom = "x-replayed-from"
+)
+
+// Header возвращает значение заголовка по ключу (последнее, если их несколько).
+func (m Message) Header(key string) (string, bool) {
+	for i := len(m.Headers) - 1; i >= 0; i-- {
+		if m.Headers[i].Key == key {
+			return string(m.Headers[i].Value), true
+		}
+	}
+	return "", false
+}
+
+// Attempt — номер текущей попытки обработки сообщения (по умолчанию 1).
+func (m Message) Attempt() int {
+	if v, ok := m.Header(HeaderAttempt); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+// Origin возвращает координаты исходного сообщения: для ретраев — из заголовков,
+// для обычных сообщений — его собственные.
+func (m Message) Origin() (topic string, partition int, offset int64) {
+	topic, partition, offset = m.Topic, m.Partition, m.Offset
+	if v, ok := m.Header(HeaderOriginalTopic); ok {
+		topic = v
+	}
+	if v, ok := m.Header(HeaderOriginalPartition); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			partition = n
+		}
+	}
+	if v, ok := m.Header(HeaderOriginalOffset); ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			offset = n
+		}
+	}
+	return topic, partition, offset
+}
+
+// OriginTimestamp — время исходного сообщения (для ретраев — из заголовка).
+func (m Message) OriginTimestamp() time.Time {
+	if v, ok := m.Header(HeaderOriginalTimestamp); ok {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms)
+		}
+	}
+	return m.Timestamp
+}
+
+// FirstFailedAt — время первой неудачной попытки; нулевое, если сообщение
+// ещё не ретраилось.
+func (m Message) FirstFailedAt() time.Time {
+	if v, ok := m.Header(HeaderFirstFailedAt); ok {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms)
+		}
+	}
+	return time.Time{}
+}
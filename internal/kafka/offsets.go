@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// CommitGroupOffsets записывает смещения группы в брокер вне сессии группы
+// (generation -1). Брокер принимает такой коммит, только пока в группе нет
+// активных участников, поэтому вызывать его нужно до NewReader.
+// offsets — следующая позиция чтения по партициям.
+func CommitGroupOffsets(ctx context.Context, brokers []string, groupID, topic string, offsets map[int]int64) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	if len(brokers) == 0 {
+		return errors.New("kafka: empty brokers")
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for p, off := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: off})
+	}
+
+	client := &kafka.Client{Addr: kafka.TCP(brokers...)}
+	resp, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			errs = append(errs, fmt.Errorf("partition %d: %w", p.Partition, p.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
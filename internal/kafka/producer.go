@@ -31,6 +31,19 @@ func (p *Producer) SendWithContext(ctx context.Context, key, value []byte) error
 	return p.writer.WriteMessages(ctx, msg)
 }
 
+// SendWithHeaders отправляет сообщение с заголовками.
+func (p *Producer) SendWithHeaders(ctx context.Context, key, value []byte, headers []Header) error {
+	msg := kafka.Message{
+		Key:   key,
+		Value: value,
+		Time:  time.Now(),
+	}
+	for _, h := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return p.writer.WriteMessages(ctx, msg)
+}
+
 func (p *Producer) Close() error {
 	return p.writer.Close()
 }
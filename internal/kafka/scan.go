@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ScanTopic читает все партиции топика от первого доступного смещения до
+// конца на момент вызова (без consumer group и коммитов) и передаёт
+// сообщения в fn. Ошибка fn прерывает чтение.
+func ScanTopic(ctx context.Context, brokers []string, topic string, fn func(Message) error) error {
+	if len(brokers) == 0 {
+		return errors.New("kafka: empty brokers")
+	}
+	client := &kafka.Client{Addr: kafka.TCP(brokers...)}
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return fmt.Errorf("metadata: %w", err)
+	}
+	if len(meta.Topics) == 0 {
+		return fmt.Errorf("topic %q not found", topic)
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		return fmt.Errorf("topic %q: %w", topic, err)
+	}
+
+	reqs := make([]kafka.OffsetRequest, 0, 2*len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		reqs = append(reqs, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+	offs, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: reqs},
+	})
+	if err != nil {
+		return fmt.Errorf("list offsets: %w", err)
+	}
+
+	for _, po := range offs.Topics[topic] {
+		if po.Error != nil {
+			return fmt.Errorf("partition %d offsets: %w", po.Partition, po.Error)
+		}
+		if po.FirstOffset >= po.LastOffset {
+			continue // пустая партиция
+		}
+		if err := scanPartition(ctx, brokers, topic, po.Partition, po.FirstOffset, po.LastOffset, fn); err != nil {
+			return fmt.Errorf("partition %d: %w", po.Partition, err)
+		}
+	}
+	return nil
+}
+
+// scanIdleTimeout — сколько ждать следующего сообщения партиции. Последнее
+// смещение до last может так и не прийти: это может быть маркер транзакции
+// или запись, удалённая компакцией, — тогда партиция считается дочитанной.
+const scanIdleTimeout = 5 * time.Second
+
+// scanPartition читает сообщения партиции в диапазоне [first, last).
+func scanPartition(ctx context.Context, brokers []string, topic string, partition int, first, last int64, fn func(Message) error) error {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+	})
+	defer r.Close()
+
+	if err := r.SetOffset(first); err != nil {
+		return err
+	}
+	for {
+		rctx, cancel := context.WithTimeout(ctx, scanIdleTimeout)
+		msg, err := r.ReadMessage(rctx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return nil // до last больше нет сообщений
+			}
+			return err
+		}
+		if err := fn(convert(msg)); err != nil {
+			return err
+		}
+		if msg.Offset >= last-1 {
+			return nil
+		}
+	}
+}
+
+func convert(msg kafka.Message) Message {
+	var headers []Header
+	for _, h := range msg.Headers {
+		headers = append(headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return Message{
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Topic:     msg.Topic,
+		Timestamp: msg.Time,
+		Headers:   headers,
+	}
+}
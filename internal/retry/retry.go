@@ -0,0 +1,88 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	skafka "github.com/segmentio/kafka-go"
+	ikafka "github.com/sillkiw/wb-l0/internal/kafka"
+)
+
+// Tier — ступень ретраев: топик и задержка перед повторной обработкой.
+type Tier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// Publisher перекладывает сообщение в топик ретраев, соответствующий номеру попытки.
+type Publisher struct {
+	w     *skafka.Writer
+	tiers []Tier
+}
+
+func NewPublisher(brokers []string, tiers []Tier) (*Publisher, error) {
+	if len(brokers) == 0 || brokers[0] == "" {
+		return nil, errors.New("retry: empty brokers")
+	}
+	if len(tiers) == 0 {
+		return nil, errors.New("retry: no tiers")
+	}
+	return &Publisher{
+		w: &skafka.Writer{
+			Addr:         skafka.TCP(brokers...),
+			RequiredAcks: skafka.RequireAll,
+			Async:        false,
+			Balancer:     &skafka.Hash{}, // один ключ — одна партиция, порядок сохраняется
+		},
+		tiers: tiers,
+	}, nil
+}
+
+func (p *Publisher) Close() error { return p.w.Close() }
+
+// Tier возвращает ступень для попытки attempt (2 — первый ретрай).
+// Попытки сверх числа ступеней идут в последнюю.
+func (p *Publisher) Tier(attempt int) Tier {
+	i := attempt - 2
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(p.tiers) {
+		i = len(p.tiers) - 1
+	}
+	return p.tiers[i]
+}
+
+// Send публикует исходный payload с тем же ключом для попытки attempt.
+// Координаты исходного сообщения сохраняются в заголовках.
+func (p *Publisher) Send(ctx context.Context, m ikafka.Message, attempt int) error {
+	topic, partition, offset := m.Origin()
+	firstFailed := m.FirstFailedAt()
+	if firstFailed.IsZero() {
+		firstFailed = time.Now()
+	}
+
+	// Если ctx без дедлайна — дадим защитный таймаут, чтобы не зависнуть при shutdown.
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 7*time.Second)
+		defer cancel()
+	}
+
+	return p.w.WriteMessages(ctx, skafka.Message{
+		Topic: p.Tier(attempt).Topic,
+		Key:   m.Key,
+		Value: m.Value,
+		Time:  time.Now(),
+		Headers: []skafka.Header{
+			{Key: ikafka.HeaderAttempt, Value: []byte(strconv.Itoa(attempt))},
+			{Key: ikafka.HeaderOriginalTopic, Value: []byte(topic)},
+			{Key: ikafka.HeaderOriginalPartition, Value: []byte(strconv.Itoa(partition))},
+			{Key: ikafka.HeaderOriginalOffset, Value: []byte(strconv.FormatInt(offset, 10))},
+			{Key: ikafka.HeaderOriginalTimestamp, Value: []byte(strconv.FormatInt(m.OriginTimestamp().UnixMilli(), 10))},
+			{Key: ikafka.HeaderFirstFailedAt, Value: []byte(strconv.FormatInt(firstFailed.UnixMilli(), 10))},
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Классы ошибок записи: permanent — повтор не поможет (нарушение ограничения,
+// некорректные данные), transient — стоит повторить позже (соединение,
+// сериализация, дедлок, перегрузка).
+var (
+	ErrPermanent = errors.New("permanent db error")
+	ErrTransient = errors.New("transient db error")
+)
+
+// DBError — классифицированная ошибка Postgres.
+type DBError struct {
+	Kind       error  // ErrPermanent или ErrTransient
+	Code       string // SQLSTATE, если ошибка пришла от сервера
+	Constraint string // имя нарушенного ограничения (для класса 23)
+	Err        error
+}
+
+func (e *DBError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%v (sqlstate %s, constraint %s): %v", e.Kind, e.Code, e.Constraint, e.Err)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("%v (sqlstate %s): %v", e.Kind, e.Code, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *DBError) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+// IntegrityViolation — нарушено ограничение целостности (SQLSTATE класса 23).
+func (e *DBError) IntegrityViolation() bool { return e.Code != "" && e.Code[:2] == "23" }
+
+func IsPermanent(err error) bool { return errors.Is(err, ErrPermanent) }
+func IsTransient(err error) bool { return errors.Is(err, ErrTransient) }
+
+// classify оборачивает ошибку в DBError, если её класс известен.
+// Неизвестные ошибки возвращаются как есть.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		switch pqErr.Code.Class() {
+		case "23", // integrity_constraint_violation: CHECK, UNIQUE, FK, NOT NULL
+			"22": // data_exception: переполнение, неверный формат
+			return &DBError{Kind: ErrPermanent, Code: code, Constraint: pqErr.Constraint, Err: err}
+		case "08", // connection_exception
+			"40", // transaction_rollback: serialization_failure, deadlock_detected
+			"53", // insufficient_resources
+			"57": // operator_intervention: admin_shutdown, cannot_connect_now
+			return &DBError{Kind: ErrTransient, Code: code, Err: err}
+		}
+		if code == "55P03" { // lock_not_available
+			return &DBError{Kind: ErrTransient, Code: code, Err: err}
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) {
+		return &DBError{Kind: ErrTransient, Err: err}
+	}
+	return err
+}
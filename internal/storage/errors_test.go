@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		permanent  bool
+		transient  bool
+		constraint string
+	}{
+		{"check violation", &pq.Error{Code: "23514", Constraint: "amount_nonneg"}, true, false, "amount_nonneg"},
+		{"unique violation", &pq.Error{Code: "23505", Constraint: "payments_order_uid_key"}, true, false, "payments_order_uid_key"},
+		{"numeric out of range", &pq.Error{Code: "22003"}, true, false, ""},
+		{"serialization failure", &pq.Error{Code: "40001"}, false, true, ""},
+		{"deadlock", &pq.Error{Code: "40P01"}, false, true, ""},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, false, true, ""},
+		{"bad conn", driver.ErrBadConn, false, true, ""},
+		{"undefined table", &pq.Error{Code: "42P01"}, false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(fmt.Errorf("upsert orders failed: %w", tt.err))
+			if IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent = %v, want %v", IsPermanent(err), tt.permanent)
+			}
+			if IsTransient(err) != tt.transient {
+				t.Errorf("IsTransient = %v, want %v", IsTransient(err), tt.transient)
+			}
+			var dbErr *DBError
+			if errors.As(err, &dbErr) && dbErr.Constraint != tt.constraint {
+				t.Errorf("constraint = %q, want %q", dbErr.Constraint, tt.constraint)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -11,85 +12,139 @@ import (
 
 var ErrNotFound = sql.ErrNoRows
 
-// GetOrder возвращает заказ целиком по order_uid.
-func (s *Storage) GetOrder(ctx context.Context, orderUID string) (domain.Order, error) {
-	var o domain.Order
+// orderSelect читает заказ вместе с доставкой, оплатой и товарами одним
+// запросом: все подзапросы видят один снимок, поэтому параллельный SaveOrder
+// не даст «рваного» заказа (новые товары со старой оплатой и т.п.).
+const orderSelect = `
+	SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
+	       o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+	       o.version_ts, o.ingested_at, o.updated_at, o.source_topic, o.source_partition, o.source_offset, o.source_key,
+	       (SELECT json_build_object(
+	                   'name', d.name, 'phone', d.phone, 'zip', d.zip, 'city', d.city,
+	                   'address', d.address, 'region', d.region, 'email', d.email)
+	          FROM deliveries d WHERE d.order_uid = o.order_uid),
+	       (SELECT json_build_object(
+	                   'transaction', p.transaction, 'request_id', p.request_id, 'currency', p.currency,
+	                   'provider', p.provider, 'amount', p.amount, 'payment_dt', p.payment_dt, 'bank', p.bank,
+	                   'delivery_cost', p.delivery_cost, 'goods_total', p.goods_total, 'custom_fee', p.custom_fee)
+	          FROM payments p WHERE p.order_uid = o.order_uid),
+	       (SELECT json_agg(json_build_object(
+	                   'chrt_id', i.chrt_id, 'track_number', i.track_number, 'price', i.price, 'rid', i.rid,
+	                   'name', i.name, 'sale', i.sale, 'size', i.size, 'total_price', i.total_price,
+	                   'nm_id', i.nm_id, 'brand', i.brand, 'status', i.status) ORDER BY i.chrt_id)
+	          FROM items i WHERE i.order_uid = o.order_uid)
+	FROM orders o`
+
+// Meta — служебные сведения о загрузке заказа.
+type Meta struct {
+	IngestedAt      *time.Time `json:"ingested_at,omitempty"` // первая запись заказа
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`  // последняя принятая версия
+	Version         *time.Time `json:"version,omitempty"`     // время исходного сообщения (version_ts)
+	SourceTopic     string     `json:"source_topic,omitempty"`
+	SourcePartition *int       `json:"source_partition,omitempty"`
+	SourceOffset    *int64     `json:"source_offset,omitempty"`
+	SourceKey       string     `json:"source_key,omitempty"`
+}
+
+// OrderWithMeta — заказ в ответе API вместе со служебными сведениями.
+// Meta живёт только здесь, а не в domain.Order: иначе DecodeStrict
+// принимал бы _meta от продюсеров.
+type OrderWithMeta struct {
+	domain.Order
+	Meta *Meta `json:"_meta,omitempty"` // в API — по ?include=meta
+}
 
-	// orders
-	err := s.db.QueryRowContext(ctx, `
-		SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
-		       delivery_service, shardkey, sm_id, date_created, oof_shard
-		FROM orders WHERE order_uid = $1
-	`, orderUID).Scan(
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanOrder разбирает строку orderSelect.
+func scanOrder(r rowScanner) (OrderWithMeta, error) {
+	var (
+		o                        OrderWithMeta
+		delivery, payment, items []byte
+
+		version           sql.NullTime
+		ingested, updated sql.NullTime
+		topic, key        sql.NullString
+		partition, offset sql.NullInt64
+	)
+	if err := r.Scan(
 		&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature, &o.CustomerID,
 		&o.DeliveryService, &o.ShardKey, &o.SmID, &o.DateCreated, &o.OofShard,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return domain.Order{}, ErrNotFound
-		}
-		return domain.Order{}, fmt.Errorf("select orders: %w", err)
+		&version, &ingested, &updated, &topic, &partition, &offset, &key,
+		&delivery, &payment, &items,
+	); err != nil {
+		return OrderWithMeta{}, err
 	}
-
-	// delivery (1:1)
-	err = s.db.QueryRowContext(ctx, `
-		SELECT name, phone, zip, city, address, region, email
-		FROM deliveries WHERE order_uid = $1
-	`, orderUID).Scan(
-		&o.Delivery.Name, &o.Delivery.Phone, &o.Delivery.Zip, &o.Delivery.City,
-		&o.Delivery.Address, &o.Delivery.Region, &o.Delivery.Email,
-	)
-	if err != nil && err != sql.ErrNoRows {
-		return domain.Order{}, fmt.Errorf("select deliveries: %w", err)
+	o.Meta = &Meta{SourceTopic: topic.String, SourceKey: key.String}
+	if version.Valid {
+		t := version.Time.UTC()
+		o.Meta.Version = &t
 	}
-
-	// payment (1:1 по order_uid)
-	err = s.db.QueryRowContext(ctx, `
-		SELECT p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,
-		       p.delivery_cost, p.goods_total, p.custom_fee
-		FROM payments p WHERE p.order_uid = $1
-	`, orderUID).Scan(
-		&o.Payment.Transaction, &o.Payment.RequestID, &o.Payment.Currency, &o.Payment.Provider,
-		&o.Payment.Amount, &o.Payment.PaymentDT, &o.Payment.Bank, &o.Payment.DeliveryCost,
-		&o.Payment.GoodsTotal, &o.Payment.CustomFee,
-	)
-	if err != nil && err != sql.ErrNoRows {
-		return domain.Order{}, fmt.Errorf("select payments: %w", err)
+	if ingested.Valid {
+		t := ingested.Time.UTC()
+		o.Meta.IngestedAt = &t
 	}
-
-	// items (1:N)
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-		FROM items WHERE order_uid = $1 ORDER BY chrt_id
-	`, orderUID)
-	if err != nil {
-		return domain.Order{}, fmt.Errorf("select items: %w", err)
+	if updated.Valid {
+		t := updated.Time.UTC()
+		o.Meta.UpdatedAt = &t
 	}
-	defer rows.Close()
-
-	o.Items = o.Items[:0]
-	for rows.Next() {
-		var it domain.Item
-		if err := rows.Scan(
-			&it.ChrtID, &it.TrackNumber, &it.Price, &it.RID, &it.Name, &it.Sale, &it.Size,
-			&it.TotalPrice, &it.NmID, &it.Brand, &it.Status,
-		); err != nil {
-			return domain.Order{}, fmt.Errorf("scan item: %w", err)
+	if partition.Valid {
+		p := int(partition.Int64)
+		o.Meta.SourcePartition = &p
+	}
+	if offset.Valid {
+		o.Meta.SourceOffset = &offset.Int64
+	}
+	if delivery != nil {
+		if err := json.Unmarshal(delivery, &o.Delivery); err != nil {
+			return OrderWithMeta{}, fmt.Errorf("decode delivery: %w", err)
 		}
-		o.Items = append(o.Items, it)
 	}
-	if err := rows.Err(); err != nil {
-		return domain.Order{}, fmt.Errorf("rows items: %w", err)
+	if payment != nil {
+		if err := json.Unmarshal(payment, &o.Payment); err != nil {
+			return OrderWithMeta{}, fmt.Errorf("decode payment: %w", err)
+		}
+	}
+	if items != nil {
+		if err := json.Unmarshal(items, &o.Items); err != nil {
+			return OrderWithMeta{}, fmt.Errorf("decode items: %w", err)
+		}
 	}
-
 	o.DateCreated = o.DateCreated.UTC()
+	return o, nil
+}
 
+// GetOrder возвращает заказ целиком по order_uid.
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (OrderWithMeta, error) {
+	o, err := scanOrder(s.db.QueryRowContext(ctx, orderSelect+` WHERE o.order_uid = $1`, orderUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return OrderWithMeta{}, ErrNotFound
+		}
+		return OrderWithMeta{}, fmt.Errorf("select order: %w", err)
+	}
 	return o, nil
 }
 
 // Вспомогательный timeout-обёртка
-func (s *Storage) GetOrderWithTimeout(parent context.Context, orderUID string, d time.Duration) (domain.Order, error) {
+func (s *Storage) GetOrderWithTimeout(parent context.Context, orderUID string, d time.Duration) (OrderWithMeta, error) {
 	ctx, cancel := context.WithTimeout(parent, d)
 	defer cancel()
 	return s.GetOrder(ctx, orderUID)
 }
+
+// OrderVersionTS возвращает orders.version_ts — время исходного сообщения
+// записанной версии заказа (нулевое, если не известно).
+func (s *Storage) OrderVersionTS(ctx context.Context, orderUID string) (time.Time, error) {
+	var v sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT version_ts FROM orders WHERE order_uid = $1`, orderUID).Scan(&v)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, ErrNotFound
+		}
+		return time.Time{}, fmt.Errorf("select order version_ts: %w", err)
+	}
+	return v.Time.UTC(), nil
+}
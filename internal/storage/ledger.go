@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ErrDuplicate — сообщение уже было применено к БД, заказ не перезаписан.
+var ErrDuplicate = errors.New("message already processed")
+
+// Origin — координаты Kafka-сообщения, из которого пришёл заказ.
+// Нулевое значение — источник неизвестен, журнал сообщений не ведётся.
+type Origin struct {
+	GroupID   string
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
+func (o Origin) IsZero() bool { return o.Topic == "" }
+
+type originKey struct {
+	groupID, topic string
+	partition      int
+	offset         int64
+}
+
+func keyOf(o Origin) originKey {
+	return originKey{groupID: o.GroupID, topic: o.Topic, partition: o.Partition, offset: o.Offset}
+}
+
+// markProcessed записывает сообщения в processed_messages и возвращает те,
+// что записаны впервые. Сообщения ниже сохранённой позиции группы считаются
+// обработанными, даже если их строки уже вычищены.
+func markProcessed(ctx context.Context, tx *sql.Tx, origins []Origin) (map[originKey]bool, error) {
+	var (
+		groups, topics []string
+		partitions     []int64
+		offsets        []int64
+	)
+	for _, o := range origins {
+		groups = append(groups, o.GroupID)
+		topics = append(topics, o.Topic)
+		partitions = append(partitions, int64(o.Partition))
+		offsets = append(offsets, o.Offset)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO processed_messages(group_id, topic, partition, "offset")
+		SELECT v.group_id, v.topic, v.partition, v."offset"
+		FROM unnest($1::text[], $2::text[], $3::int[], $4::bigint[])
+		     AS v(group_id, topic, partition, "offset")
+		WHERE NOT EXISTS (
+			SELECT 1 FROM consumer_offsets c
+			WHERE c.group_id = v.group_id AND c.topic = v.topic
+			  AND c.partition = v.partition AND v."offset" < c.next_offset
+		)
+		ON CONFLICT DO NOTHING
+		RETURNING group_id, topic, partition, "offset"
+	`, pq.Array(groups), pq.Array(topics), pq.Array(partitions), pq.Array(offsets))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fresh := make(map[originKey]bool, len(origins))
+	for rows.Next() {
+		var k originKey
+		if err := rows.Scan(&k.groupID, &k.topic, &k.partition, &k.offset); err != nil {
+			return nil, err
+		}
+		fresh[k] = true
+	}
+	return fresh, rows.Err()
+}
+
+// StoreOffsets сдвигает позиции групп: next_offset = offset+1 (только вперёд)
+// и вычищает из processed_messages записи ниже новой позиции.
+// Вызывается перед коммитом смещений в Kafka.
+func (s *Storage) StoreOffsets(ctx context.Context, positions []Origin) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, p := range positions {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO consumer_offsets(group_id, topic, partition, next_offset)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (group_id, topic, partition) DO UPDATE SET
+				next_offset = GREATEST(consumer_offsets.next_offset, EXCLUDED.next_offset),
+				updated_at  = now()
+		`, p.GroupID, p.Topic, p.Partition, p.Offset+1); err != nil {
+			return fmt.Errorf("upsert consumer offset failed: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM processed_messages
+			WHERE group_id = $1 AND topic = $2 AND partition = $3 AND "offset" <= $4
+		`, p.GroupID, p.Topic, p.Partition, p.Offset); err != nil {
+			return fmt.Errorf("prune processed messages failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit failed: %w", err)
+	}
+	return nil
+}
+
+// LoadOffsets возвращает сохранённые позиции группы по партициям топика.
+func (s *Storage) LoadOffsets(ctx context.Context, groupID, topic string) (map[int]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT partition, next_offset FROM consumer_offsets
+		WHERE group_id = $1 AND topic = $2
+	`, groupID, topic)
+	if err != nil {
+		return nil, fmt.Errorf("select consumer offsets: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int]int64)
+	for rows.Next() {
+		var p int
+		var next int64
+		if err := rows.Scan(&p, &next); err != nil {
+			return nil, fmt.Errorf("scan consumer offset: %w", err)
+		}
+		out[p] = next
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows consumer offsets: %w", err)
+	}
+	return out, nil
+}
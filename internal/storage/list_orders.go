@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderCursor — позиция для keyset-пагинации по (date_created, order_uid).
+// Нулевое значение означает «с самого начала».
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+func (c OrderCursor) IsZero() bool { return c.OrderUID == "" }
+
+// ErrBadCursor — строку курсора не удалось разобрать.
+var ErrBadCursor = errors.New("bad cursor")
+
+// Encode упаковывает курсор в непрозрачную строку для API.
+func (c OrderCursor) Encode() string {
+	if c.IsZero() {
+		return ""
+	}
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseOrderCursor разбирает строку, полученную из OrderCursor.Encode.
+// Пустая строка — нулевой курсор.
+func ParseOrderCursor(s string) (OrderCursor, error) {
+	if s == "" {
+		return OrderCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return OrderCursor{}, ErrBadCursor
+	}
+	ts, uid, ok := strings.Cut(string(raw), "|")
+	if !ok || uid == "" {
+		return OrderCursor{}, ErrBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return OrderCursor{}, ErrBadCursor
+	}
+	return OrderCursor{DateCreated: t, OrderUID: uid}, nil
+}
+
+// OrderFilter — условия выборки заказов. Нулевые поля не фильтруют.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	From            time.Time // date_created >= From
+	To              time.Time // date_created < To
+}
+
+// ListOrders возвращает до limit заказов под фильтр, от новых к старым,
+// начиная сразу после after. Заказы читаются целиком одним запросом.
+func (s *Storage) ListOrders(ctx context.Context, f OrderFilter, after OrderCursor, limit int) ([]OrderWithMeta, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v ...any) {
+		n := make([]any, len(v))
+		for i := range v {
+			args = append(args, v[i])
+			n[i] = len(args)
+		}
+		where = append(where, fmt.Sprintf(cond, n...))
+	}
+	if f.CustomerID != "" {
+		add("o.customer_id = $%d", f.CustomerID)
+	}
+	if f.TrackNumber != "" {
+		add("o.track_number = $%d", f.TrackNumber)
+	}
+	if f.DeliveryService != "" {
+		add("o.delivery_service = $%d", f.DeliveryService)
+	}
+	if !f.From.IsZero() {
+		add("o.date_created >= $%d", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("o.date_created < $%d", f.To.UTC())
+	}
+	if !after.IsZero() {
+		add("(o.date_created, o.order_uid) < ($%d, $%d)", after.DateCreated.UTC(), after.OrderUID)
+	}
+
+	query := orderSelect
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY o.date_created DESC, o.order_uid DESC LIMIT $%d`, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []OrderWithMeta
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows orders: %w", err)
+	}
+	return orders, nil
+}
+
+// RecentOrders возвращает до limit последних заказов без фильтров.
+func (s *Storage) RecentOrders(ctx context.Context, after OrderCursor, limit int) ([]OrderWithMeta, error) {
+	return s.ListOrders(ctx, OrderFilter{}, after, limit)
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderCursorRoundTrip(t *testing.T) {
+	c := OrderCursor{
+		DateCreated: time.Date(2025, 3, 4, 5, 6, 7, 890123000, time.UTC),
+		OrderUID:    "ord_01HZX|with-pipe",
+	}
+	got, err := ParseOrderCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !got.DateCreated.Equal(c.DateCreated) || got.OrderUID != c.OrderUID {
+		t.Fatalf("round trip = %+v, want %+v", got, c)
+	}
+
+	if z, err := ParseOrderCursor(""); err != nil || !z.IsZero() {
+		t.Fatalf("empty cursor = %+v, %v", z, err)
+	}
+	for _, bad := range []string{"!!!", "bm8tcGlwZQ", "eHxvcmQ"} { // мусор, "no-pipe", "x|ord"
+		if _, err := ParseOrderCursor(bad); err != ErrBadCursor {
+			t.Fatalf("ParseOrderCursor(%q) err = %v, want ErrBadCursor", bad, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// LookupField — идентификатор, по которому ищется заказ, когда order_uid неизвестен.
+type LookupField string
+
+const (
+	LookupTrackNumber LookupField = "track_number" // трек-номер заказа (WB...)
+	LookupTransaction LookupField = "transaction"  // ID транзакции оплаты (txn_...)
+	LookupRequestID   LookupField = "request_id"   // ID запроса оплаты (req_...)
+	LookupRID         LookupField = "rid"          // rid товара (RID-...)
+)
+
+// LookupFields — все поддерживаемые идентификаторы.
+var LookupFields = []LookupField{LookupTrackNumber, LookupTransaction, LookupRequestID, LookupRID}
+
+// ErrBadLookupField — неизвестный идентификатор.
+var ErrBadLookupField = errors.New("unknown lookup field")
+
+var lookupConds = map[LookupField]string{
+	LookupTrackNumber: `o.track_number = $1`,
+	LookupTransaction: `o.order_uid IN (SELECT order_uid FROM payments WHERE transaction = $1)`,
+	LookupRequestID:   `o.order_uid IN (SELECT order_uid FROM payments WHERE request_id = $1)`,
+	LookupRID:         `o.order_uid IN (SELECT order_uid FROM items WHERE rid = $1)`,
+}
+
+// LookupOrders возвращает до limit заказов, у которых field равно value,
+// от новых к старым.
+func (s *Storage) LookupOrders(ctx context.Context, field LookupField, value string, limit int) ([]OrderWithMeta, error) {
+	cond, ok := lookupConds[field]
+	if !ok {
+		return nil, ErrBadLookupField
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		orderSelect+` WHERE `+cond+` ORDER BY o.date_created DESC, o.order_uid DESC LIMIT $2`,
+		value, limit)
+	if err != nil {
+		return nil, fmt.Errorf("lookup orders by %s: %w", field, err)
+	}
+	defer rows.Close()
+
+	var orders []OrderWithMeta
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows orders: %w", err)
+	}
+	return orders, nil
+}
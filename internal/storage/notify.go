@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OrdersChangedChannel — канал NOTIFY, в который SaveOrders пишет order_uid
+// каждого применённого заказа. Уведомления уходят только при COMMIT.
+const OrdersChangedChannel = "orders_changed"
+
+func notifyChanged(ctx context.Context, tx *sql.Tx, uids []string) error {
+	_, err := tx.ExecContext(ctx,
+		`SELECT pg_notify($1, u) FROM unnest($2::text[]) AS u`,
+		OrdersChangedChannel, pq.Array(uids),
+	)
+	return err
+}
+
+// listenPingInterval — как часто проверять соединение LISTEN: без трафика
+// обрыв иначе можно не заметить.
+const listenPingInterval = 90 * time.Second
+
+// ListenOrderChanges слушает OrdersChangedChannel до отмены ctx: onChange
+// вызывается на каждый изменённый order_uid. Соединение переподключается
+// само; после любого разрыва вызывается onGap — уведомления за время
+// разрыва потеряны, и кэш надо считать целиком устаревшим.
+func ListenOrderChanges(ctx context.Context, dsn string, log *slog.Logger, onChange func(uid string), onGap func()) error {
+	l := pq.NewListener(dsn, time.Second, 30*time.Second, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnected:
+			log.Info("orders listener connected")
+		case pq.ListenerEventDisconnected:
+			log.Warn("orders listener disconnected", slog.Any("err", err))
+		case pq.ListenerEventReconnected:
+			log.Info("orders listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Warn("orders listener connect failed", slog.Any("err", err))
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(OrdersChangedChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(listenPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			if n == nil {
+				// pq присылает nil после переподключения
+				onGap()
+				continue
+			}
+			onChange(n.Extra)
+		case <-ping.C:
+			go func() {
+				if err := l.Ping(); err != nil {
+					log.Warn("orders listener ping failed", slog.Any("err", err))
+				}
+			}()
+		}
+	}
+}
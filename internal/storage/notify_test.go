@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/generator"
+)
+
+func TestListenOrderChanges(t *testing.T) {
+	s, _ := testStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan string, 16)
+	gaps := make(chan struct{}, 1)
+	go func() {
+		_ = ListenOrderChanges(ctx, os.Getenv("TEST_DATABASE_URL"), slog.New(slog.NewTextHandler(io.Discard, nil)),
+			func(uid string) { changed <- uid },
+			func() {
+				select {
+				case gaps <- struct{}{}:
+				default:
+				}
+			})
+	}()
+	waitListener(t, s)
+
+	o := generator.NewFakeOrder(1)
+	if err := s.SaveOrder(ctx, Record{Order: o}); err != nil {
+		t.Fatalf("save order: %v", err)
+	}
+	for uid := ""; uid != o.OrderUID; {
+		select {
+		case uid = <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification for %s", o.OrderUID)
+		}
+	}
+
+	// обрыв соединения LISTEN: после переподключения — onGap
+	if _, err := s.db.ExecContext(ctx,
+		`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query = 'LISTEN "orders_changed"'`); err != nil {
+		t.Fatalf("terminate listener: %v", err)
+	}
+	select {
+	case <-gaps:
+	case <-time.After(10 * time.Second):
+		t.Fatal("onGap not called after reconnect")
+	}
+}
+
+// waitListener ждёт, пока соединение LISTEN появится в pg_stat_activity.
+func waitListener(t *testing.T, s *Storage) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var n int
+		err := s.db.QueryRow(`SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN "orders_changed"'`).Scan(&n)
+		if err != nil {
+			t.Fatalf("pg_stat_activity: %v", err)
+		}
+		if n > 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("listener did not connect")
+}
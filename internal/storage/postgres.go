@@ -3,9 +3,12 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sillkiw/wb-l0/internal/dbutils"
 	"github.com/sillkiw/wb-l0/internal/domain"
 )
@@ -28,111 +31,311 @@ func NewStorage(connStr string) (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveOrder(ctx context.Context, order domain.Order) error {
+// ErrStale — в БД более новая версия заказа, запись пропущена.
+var ErrStale = errors.New("stale order version")
+
+// Record — заказ вместе с сообщением-источником.
+type Record struct {
+	Order  domain.Order
+	Origin Origin
+	Source Source // пишется в историю версий
+
+	// Version — монотонная версия заказа (время исходного Kafka-сообщения).
+	// Нулевое значение — момент записи.
+	Version time.Time
+}
+
+// SaveResult — итог SaveOrders.
+type SaveResult struct {
+	Saved      int   // заказов записано
+	Duplicates int   // сообщений пропущено как уже обработанные
+	Stale      []int // индексы записей, пропущенных как устаревшие
+}
+
+// SaveOrder сохраняет один заказ (UPSERT по всем таблицам в одной транзакции).
+// Если сообщение origin уже применено, возвращает ErrDuplicate; если в БД
+// лежит более новая версия заказа — ErrStale.
+func (s *Storage) SaveOrder(ctx context.Context, rec Record) error {
+	res, err := s.SaveOrders(ctx, []Record{rec})
+	if err != nil {
+		return err
+	}
+	if res.Duplicates > 0 {
+		return ErrDuplicate
+	}
+	if len(res.Stale) > 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+var (
+	orderCols = []string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+		"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "version_ts",
+		"ingested_at", "updated_at", "source_topic", "source_partition", "source_offset", "source_key",
+	}
+	deliveryCols = []string{
+		"order_uid", "name", "phone", "zip", "city", "address", "region", "email",
+	}
+	paymentCols = []string{
+		"transaction", "order_uid", "request_id", "currency", "provider", "amount", "payment_dt", "bank",
+		"delivery_cost", "goods_total", "custom_fee",
+	}
+	itemCols = []string{
+		"order_uid", "chrt_id", "track_number", "price", "rid", "name",
+		"sale", "size", "total_price", "nm_id", "brand", "status",
+	}
+)
+
+// orderUpdateCols — колонки orders, обновляемые при конфликте.
+// ingested_at — время первой записи, его не трогаем.
+var orderUpdateCols = slices.DeleteFunc(slices.Clone(orderCols[1:]), func(c string) bool { return c == "ingested_at" })
+
+// SaveOrders сохраняет пачку заказов в одной транзакции: по одному
+// многострочному UPSERT на таблицу вместо четырёх запросов на каждый заказ.
+// В той же транзакции сообщения-источники отмечаются в processed_messages,
+// уже применённые ранее пропускаются.
+// Заказ не перезаписывается версией старше той, что уже в БД (orders.version_ts);
+// такие записи попадают в SaveResult.Stale. Если один order_uid встречается
+// в пачке несколько раз, побеждает самая новая версия.
+// Ошибки классифицируются (см. ErrPermanent, ErrTransient).
+func (s *Storage) SaveOrders(ctx context.Context, recs []Record) (SaveResult, error) {
+	res, err := s.saveOrders(ctx, recs)
+	return res, classify(err)
+}
+
+func (s *Storage) saveOrders(ctx context.Context, recs []Record) (SaveResult, error) {
+	var res SaveResult
+	if len(recs) == 0 {
+		return res, nil
+	}
+
+	// версия по умолчанию — момент записи (копия, чтобы не менять срез вызывающего)
+	recs = slices.Clone(recs)
+	now := time.Now()
+	for i := range recs {
+		if recs[i].Version.IsZero() {
+			recs[i].Version = now
+		}
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("tx begin failed: %w", err)
+		return res, fmt.Errorf("tx begin failed: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// --- orders (UPSERT 1:1) ---
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO orders(
-			order_uid, track_number, entry, locale, internal_signature, customer_id,
-			delivery_service, shardkey, sm_id, date_created, oof_shard
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
-		ON CONFLICT (order_uid) DO UPDATE SET
-			track_number       = EXCLUDED.track_number,
-			entry              = EXCLUDED.entry,
-			locale             = EXCLUDED.locale,
-			internal_signature = EXCLUDED.internal_signature,
-			customer_id        = EXCLUDED.customer_id,
-			delivery_service   = EXCLUDED.delivery_service,
-			shardkey           = EXCLUDED.shardkey,
-			sm_id              = EXCLUDED.sm_id,
-			date_created       = EXCLUDED.date_created,
-			oof_shard          = EXCLUDED.oof_shard
-	`, order.OrderUID, order.TrackNumber, order.Entry, order.Locale,
-		order.InternalSignature, order.CustomerID, order.DeliveryService,
-		order.ShardKey, order.SmID, order.DateCreated.UTC(), order.OofShard)
-	if err != nil {
-		return fmt.Errorf("upsert order failed: %w", err)
+	// --- журнал сообщений ---
+	pending := make([]int, 0, len(recs)) // индексы recs, которые ещё предстоит применить
+	var origins []Origin
+	for _, r := range recs {
+		if !r.Origin.IsZero() {
+			origins = append(origins, r.Origin)
+		}
+	}
+	var fresh map[originKey]bool
+	if len(origins) > 0 {
+		if fresh, err = markProcessed(ctx, tx, origins); err != nil {
+			return res, fmt.Errorf("mark processed failed: %w", err)
+		}
+	}
+	for i, r := range recs {
+		if !r.Origin.IsZero() && !fresh[keyOf(r.Origin)] {
+			res.Duplicates++
+			continue
+		}
+		pending = append(pending, i)
 	}
 
-	// --- deliveries (UPSERT 1:1) ---
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO deliveries(order_uid, name, phone, zip, city, address, region, email)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-		ON CONFLICT (order_uid) DO UPDATE SET
-			name   = EXCLUDED.name,
-			phone  = EXCLUDED.phone,
-			zip    = EXCLUDED.zip,
-			city   = EXCLUDED.city,
-			address= EXCLUDED.address,
-			region = EXCLUDED.region,
-			email  = EXCLUDED.email
-	`, order.OrderUID, order.Delivery.Name, order.Delivery.Phone,
-		order.Delivery.Zip, order.Delivery.City, order.Delivery.Address,
-		order.Delivery.Region, order.Delivery.Email)
-	if err != nil {
-		return fmt.Errorf("upsert delivery failed: %w", err)
+	// --- одна версия на order_uid ---
+	pending, stale := newestPerOrder(recs, pending)
+	res.Stale = append(res.Stale, stale...)
+	if len(pending) == 0 {
+		// нечего применять - фиксируем журнал
+		return res, tx.Commit()
 	}
 
-	// --- payments (UPSERT по PK transaction) ---
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO payments(
-			transaction, order_uid, request_id, currency, provider, amount, payment_dt, bank,
-			delivery_cost, goods_total, custom_fee
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, $11)
-		ON CONFLICT (transaction) DO UPDATE SET
-			order_uid     = EXCLUDED.order_uid,
-			request_id 	  = EXCLUDED.request_id,
-			currency      = EXCLUDED.currency,
-			provider      = EXCLUDED.provider,
-			amount        = EXCLUDED.amount,
-			payment_dt    = EXCLUDED.payment_dt,
-			bank          = EXCLUDED.bank,
-			delivery_cost = EXCLUDED.delivery_cost,
-			goods_total   = EXCLUDED.goods_total,
-			custom_fee    = EXCLUDED.custom_fee
-	`, order.Payment.Transaction, order.OrderUID, order.Payment.RequestID, order.Payment.Currency,
-		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDT,
-		order.Payment.Bank, order.Payment.DeliveryCost,
-		order.Payment.GoodsTotal, order.Payment.CustomFee)
+	// --- orders (UPSERT 1:1 с защитой от устаревших версий) ---
+	applied, err := upsertOrders(ctx, tx, recs, pending)
 	if err != nil {
-		return fmt.Errorf("upsert payment failed: %w", err)
+		return res, fmt.Errorf("upsert orders failed: %w", err)
 	}
-
-	// --- items ---
-	// Удаление старых заказов
-	if _, err := tx.ExecContext(ctx, `DELETE FROM items WHERE order_uid = $1`, order.OrderUID); err != nil {
-		return fmt.Errorf("delete items failed: %w", err)
+	kept := pending[:0]
+	for _, i := range pending {
+		if applied[recs[i].Order.OrderUID] {
+			kept = append(kept, i)
+		} else {
+			res.Stale = append(res.Stale, i)
+		}
+	}
+	pending = kept
+	if len(pending) == 0 {
+		return res, tx.Commit()
 	}
 
-	// батч-вставка новых (если они есть)
-	if len(order.Items) > 0 {
-		cols := []string{
-			"order_uid", "chrt_id", "track_number", "price", "rid", "name",
-			"sale", "size", "total_price", "nm_id", "brand", "status",
-		}
-		rows := make([][]interface{}, 0, len(order.Items))
-		for _, it := range order.Items {
-			rows = append(rows, []interface{}{
-				order.OrderUID, it.ChrtID, it.TrackNumber, it.Price,
+	var (
+		uids                   = make([]string, 0, len(pending))
+		dlvRows                [][]interface{}
+		paymentRows, itemsRows [][]interface{}
+		seenTxn                = make(map[string]int, len(pending))
+	)
+	for _, i := range pending {
+		o := recs[i].Order
+		uids = append(uids, o.OrderUID)
+		dlvRows = append(dlvRows, []interface{}{
+			o.OrderUID, o.Delivery.Name, o.Delivery.Phone,
+			o.Delivery.Zip, o.Delivery.City, o.Delivery.Address,
+			o.Delivery.Region, o.Delivery.Email,
+		})
+		// одна транзакция не может дважды обновиться в одном UPSERT
+		row := []interface{}{
+			o.Payment.Transaction, o.OrderUID, o.Payment.RequestID, o.Payment.Currency,
+			o.Payment.Provider, o.Payment.Amount, o.Payment.PaymentDT,
+			o.Payment.Bank, o.Payment.DeliveryCost,
+			o.Payment.GoodsTotal, o.Payment.CustomFee,
+		}
+		if j, ok := seenTxn[o.Payment.Transaction]; ok {
+			paymentRows[j] = row
+		} else {
+			seenTxn[o.Payment.Transaction] = len(paymentRows)
+			paymentRows = append(paymentRows, row)
+		}
+		for _, it := range o.Items {
+			itemsRows = append(itemsRows, []interface{}{
+				o.OrderUID, it.ChrtID, it.TrackNumber, it.Price,
 				it.RID, it.Name, it.Sale, it.Size,
 				it.TotalPrice, it.NmID, it.Brand, it.Status,
 			})
 		}
+	}
+
+	// --- deliveries (UPSERT 1:1) ---
+	if err := upsertRows(ctx, tx, "deliveries", deliveryCols, dlvRows, "order_uid"); err != nil {
+		return res, fmt.Errorf("upsert deliveries failed: %w", err)
+	}
+
+	// --- payments (UPSERT по PK transaction) ---
+	if err := upsertRows(ctx, tx, "payments", paymentCols, paymentRows, "transaction"); err != nil {
+		return res, fmt.Errorf("upsert payments failed: %w", err)
+	}
+
+	// --- items ---
+	// Удаление старых позиций
+	if _, err := tx.ExecContext(ctx, `DELETE FROM items WHERE order_uid = ANY($1)`, pq.Array(uids)); err != nil {
+		return res, fmt.Errorf("delete items failed: %w", err)
+	}
 
-		query, args := dbutils.BuildBatchInsert("items", cols, rows)
+	// батч-вставка новых (если они есть)
+	for _, chunk := range dbutils.ChunkRows(itemCols, itemsRows) {
+		query, args := dbutils.BuildBatchInsert("items", itemCols, chunk)
 		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
-			return fmt.Errorf("insert items failed: %w", err)
+			return res, fmt.Errorf("insert items failed: %w", err)
 		}
 	}
 
+	// --- история версий ---
+	if err := insertVersions(ctx, tx, recs, pending); err != nil {
+		return res, fmt.Errorf("insert order versions failed: %w", err)
+	}
+
+	// --- уведомление кэшей веб-сервисов (доставляется при commit) ---
+	if err := notifyChanged(ctx, tx, uids); err != nil {
+		return res, fmt.Errorf("notify orders changed failed: %w", err)
+	}
+
 	// --- commit ---
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("tx commit failed: %w", err)
+		return res, fmt.Errorf("tx commit failed: %w", err)
+	}
+	res.Saved = len(pending)
+	return res, nil
+}
+
+// upsertOrders пишет строки orders и возвращает order_uid, которые действительно
+// вставлены или обновлены. Строка с более новой version_ts не перезаписывается.
+func upsertOrders(ctx context.Context, tx *sql.Tx, recs []Record, idx []int) (map[string]bool, error) {
+	now := time.Now().UTC()
+	rows := make([][]interface{}, 0, len(idx))
+	for _, i := range idx {
+		o, src := recs[i].Order, recs[i].Source
+		var topic, partition, offset, key interface{}
+		if !src.IsZero() {
+			topic, partition, offset = src.Topic, src.Partition, src.Offset
+		}
+		if src.Key != "" {
+			key = src.Key
+		}
+		rows = append(rows, []interface{}{
+			o.OrderUID, o.TrackNumber, o.Entry, o.Locale,
+			o.InternalSignature, o.CustomerID, o.DeliveryService,
+			o.ShardKey, o.SmID, o.DateCreated.UTC(), o.OofShard, recs[i].Version.UTC(),
+			now, now, topic, partition, offset, key,
+		})
+	}
+
+	applied := make(map[string]bool, len(rows))
+	for _, chunk := range dbutils.ChunkRows(orderCols, rows) {
+		query, args := dbutils.BuildBatchUpsert("orders", orderCols, chunk, "order_uid", orderUpdateCols)
+		query += ` WHERE orders.version_ts IS NULL OR orders.version_ts <= EXCLUDED.version_ts
+			RETURNING order_uid`
+		res, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for res.Next() {
+			var uid string
+			if err := res.Scan(&uid); err != nil {
+				res.Close()
+				return nil, err
+			}
+			applied[uid] = true
+		}
+		res.Close()
+		if err := res.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return applied, nil
+}
+
+// newestPerOrder оставляет по одной записи на order_uid — с самой новой версией
+// (при равенстве — более позднюю в пачке). Вытесненные более новой версией
+// записи возвращаются как устаревшие.
+func newestPerOrder(recs []Record, idx []int) (kept, stale []int) {
+	best := make(map[string]int, len(idx))
+	for _, i := range idx {
+		uid := recs[i].Order.OrderUID
+		if j, ok := best[uid]; !ok || !recs[i].Version.Before(recs[j].Version) {
+			best[uid] = i
+		}
+	}
+	for _, i := range idx {
+		j := best[recs[i].Order.OrderUID]
+		switch {
+		case j == i:
+			kept = append(kept, i)
+		case recs[i].Version.Before(recs[j].Version):
+			stale = append(stale, i)
+		}
+	}
+	return kept, stale
+}
+
+// upsertRows выполняет многострочный UPSERT порциями в пределах лимита параметров.
+// Обновляются все колонки, кроме ключа конфликта.
+func upsertRows(ctx context.Context, tx *sql.Tx, table string, cols []string, rows [][]interface{}, conflict string) error {
+	update := make([]string, 0, len(cols)-1)
+	for _, c := range cols {
+		if c != conflict {
+			update = append(update, c)
+		}
+	}
+	for _, chunk := range dbutils.ChunkRows(cols, rows) {
+		query, args := dbutils.BuildBatchUpsert(table, cols, chunk, conflict, update)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sillkiw/wb-l0/internal/domain"
+)
+
+func TestNewestPerOrder(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := func(uid string, v time.Time) Record {
+		return Record{Order: domain.Order{OrderUID: uid}, Version: v}
+	}
+	recs := []Record{
+		rec("a", t0.Add(2*time.Second)), // 0: новее, остаётся
+		rec("a", t0),                    // 1: старее — stale
+		rec("b", t0),                    // 2: та же версия, что у 3 — вытеснен без stale
+		rec("b", t0),                    // 3: более поздний в пачке — остаётся
+		rec("c", t0),                    // 4
+	}
+
+	kept, stale := newestPerOrder(recs, []int{0, 1, 2, 3, 4})
+	if want := []int{0, 3, 4}; !slices.Equal(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if want := []int{1}; !slices.Equal(stale, want) {
+		t.Errorf("stale = %v, want %v", stale, want)
+	}
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sillkiw/wb-l0/internal/domain"
+)
+
+// Source — Kafka-сообщение, из которого пришла версия заказа. Для сообщений
+// из топиков ретраев — координаты исходного сообщения основного топика.
+// Нулевое значение — источник неизвестен (например, запись из dlq-replay).
+type Source struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Key       string `json:"key,omitempty"` // ключ сообщения
+}
+
+func (s Source) IsZero() bool { return s.Topic == "" }
+
+// OrderVersion — одна принятая версия заказа из order_versions.
+type OrderVersion struct {
+	Version    int          `json:"version"`
+	VersionTS  time.Time    `json:"version_ts"`
+	IngestedAt time.Time    `json:"ingested_at"`
+	Source     *Source      `json:"source,omitempty"`
+	Order      domain.Order `json:"order"`
+}
+
+// insertVersions дописывает в order_versions применённые записи recs[idx].
+// Номер версии — следующий за последним для заказа; строка orders уже
+// заблокирована UPSERT'ом этой транзакции, так что номера не пересекаются.
+func insertVersions(ctx context.Context, tx *sql.Tx, recs []Record, idx []int) error {
+	var (
+		uids, versions, payloads, topics, keys []string
+		partitions                             []int64
+		offsets                                []int64
+	)
+	for _, i := range idx {
+		r := recs[i]
+		payload, err := json.Marshal(r.Order)
+		if err != nil {
+			return fmt.Errorf("marshal order %s: %w", r.Order.OrderUID, err)
+		}
+		part, off := int64(-1), int64(-1)
+		if !r.Source.IsZero() {
+			part, off = int64(r.Source.Partition), r.Source.Offset
+		}
+		uids = append(uids, r.Order.OrderUID)
+		versions = append(versions, r.Version.UTC().Format(time.RFC3339Nano))
+		payloads = append(payloads, string(payload))
+		topics = append(topics, r.Source.Topic)
+		keys = append(keys, r.Source.Key)
+		partitions = append(partitions, part)
+		offsets = append(offsets, off)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO order_versions(order_uid, version, version_ts, payload,
+		                           source_topic, source_partition, source_offset, source_key)
+		SELECT v.uid,
+		       COALESCE((SELECT max(ov.version) FROM order_versions ov WHERE ov.order_uid = v.uid), 0) + 1,
+		       v.ts, v.payload, NULLIF(v.topic, ''), NULLIF(v.part, -1), NULLIF(v.off, -1), NULLIF(v.key, '')
+		FROM unnest($1::text[], $2::timestamptz[], $3::jsonb[], $4::text[], $5::int[], $6::bigint[], $7::text[])
+		     AS v(uid, ts, payload, topic, part, off, key)
+	`, pq.Array(uids), pq.Array(versions), pq.Array(payloads), pq.Array(topics),
+		pq.Array(partitions), pq.Array(offsets), pq.Array(keys))
+	return err
+}
+
+const versionCols = `version, version_ts, ingested_at, source_topic, source_partition, source_offset, source_key, payload`
+
+func scanVersion(r rowScanner) (OrderVersion, error) {
+	var (
+		v         OrderVersion
+		topic     sql.NullString
+		partition sql.NullInt64
+		offset    sql.NullInt64
+		key       sql.NullString
+		payload   []byte
+	)
+	if err := r.Scan(&v.Version, &v.VersionTS, &v.IngestedAt, &topic, &partition, &offset, &key, &payload); err != nil {
+		return OrderVersion{}, err
+	}
+	if err := json.Unmarshal(payload, &v.Order); err != nil {
+		return OrderVersion{}, fmt.Errorf("decode order version: %w", err)
+	}
+	if topic.Valid {
+		v.Source = &Source{Topic: topic.String, Partition: int(partition.Int64), Offset: offset.Int64, Key: key.String}
+	}
+	v.VersionTS = v.VersionTS.UTC()
+	v.IngestedAt = v.IngestedAt.UTC()
+	return v, nil
+}
+
+// OrderHistory возвращает до limit последних версий заказа, от новых к старым.
+func (s *Storage) OrderHistory(ctx context.Context, orderUID string, limit int) ([]OrderVersion, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+versionCols+`
+		FROM order_versions WHERE order_uid = $1
+		ORDER BY version DESC LIMIT $2
+	`, orderUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select order versions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []OrderVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order version: %w", err)
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows order versions: %w", err)
+	}
+	return out, nil
+}
+
+// OrderVersion возвращает версию заказа по номеру; version <= 0 — последнюю.
+// Нет такой версии — ErrNotFound.
+func (s *Storage) OrderVersion(ctx context.Context, orderUID string, version int) (OrderVersion, error) {
+	query := `SELECT ` + versionCols + ` FROM order_versions WHERE order_uid = $1`
+	args := []any{orderUID}
+	if version > 0 {
+		query += ` AND version = $2`
+		args = append(args, version)
+	} else {
+		query += ` ORDER BY version DESC LIMIT 1`
+	}
+
+	v, err := scanVersion(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return OrderVersion{}, ErrNotFound
+		}
+		return OrderVersion{}, fmt.Errorf("select order version: %w", err)
+	}
+	return v, nil
+}
+
+// OrderAsOf возвращает версию заказа, которая была последней записанной
+// на момент t (по ingested_at). Если до t заказ не записывался — ErrNotFound.
+func (s *Storage) OrderAsOf(ctx context.Context, orderUID string, t time.Time) (OrderVersion, error) {
+	v, err := scanVersion(s.db.QueryRowContext(ctx, `
+		SELECT `+versionCols+`
+		FROM order_versions
+		WHERE order_uid = $1 AND ingested_at <= $2
+		ORDER BY ingested_at DESC, version DESC LIMIT 1
+	`, orderUID, t.UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return OrderVersion{}, ErrNotFound
+		}
+		return OrderVersion{}, fmt.Errorf("select order version as of: %w", err)
+	}
+	return v, nil
+}
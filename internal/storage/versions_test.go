@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOrderAsOf(t *testing.T) {
+	s, o := testStorage(t) // первая версия записана здесь
+	ctx := context.Background()
+
+	before := time.Now().Add(-time.Hour)
+	time.Sleep(50 * time.Millisecond)
+	between := time.Now()
+	time.Sleep(50 * time.Millisecond)
+
+	updated := o
+	updated.Delivery.City = o.Delivery.City + " (updated)"
+	if err := s.SaveOrder(ctx, Record{Order: updated, Version: time.Now()}); err != nil {
+		t.Fatalf("save second version: %v", err)
+	}
+
+	v, err := s.OrderAsOf(ctx, o.OrderUID, between)
+	if err != nil {
+		t.Fatalf("OrderAsOf(between): %v", err)
+	}
+	if v.Version != 1 || v.Order.Delivery.City != o.Delivery.City {
+		t.Fatalf("OrderAsOf(between) = version %d, city %q; want version 1, %q", v.Version, v.Order.Delivery.City, o.Delivery.City)
+	}
+
+	v, err = s.OrderAsOf(ctx, o.OrderUID, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("OrderAsOf(now): %v", err)
+	}
+	if v.Version != 2 || v.Order.Delivery.City != updated.Delivery.City {
+		t.Fatalf("OrderAsOf(now) = version %d, city %q; want version 2", v.Version, v.Order.Delivery.City)
+	}
+
+	if _, err := s.OrderAsOf(ctx, o.OrderUID, before); err != ErrNotFound {
+		t.Fatalf("OrderAsOf(before first version): err = %v, want ErrNotFound", err)
+	}
+}
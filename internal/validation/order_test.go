@@ -158,3 +158,12 @@ func TestValidateOrder_CurrencyFormat(t *testing.T) {
 		t.Fatalf("expected format error on payment.currency, got: %v", me)
 	}
 }
+
+// _meta — только для ответов API, продюсер его присылать не может
+func TestDecodeStrict_RejectsMeta(t *testing.T) {
+	var o domain.Order
+	err := DecodeStrict([]byte(`{"order_uid":"ord_1","_meta":{"source_topic":"orders"}}`), &o)
+	if err == nil {
+		t.Fatal("expected unknown field error for _meta")
+	}
+}